@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/panbeh/otp-backend/internal/config"
+)
+
+const configUsage = `usage: api [-config FILE] [-set KEY=VALUE ...] config <command>
+
+commands:
+  check       print the effective configuration, secrets redacted, and report
+              every invalid setting
+`
+
+// runConfig implements the "config" subcommand and returns the process exit code.
+// It needs no database, so it runs before anything connects.
+func runConfig(opts config.Options, args []string, out io.Writer) int {
+	if len(args) != 1 || args[0] != "check" {
+		fmt.Fprint(os.Stderr, configUsage)
+		return 2
+	}
+
+	settings, err := config.Check(opts)
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	for _, s := range settings {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Key, s.Value, s.Source)
+	}
+	_ = w.Flush()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config check: %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(out, "configuration is valid")
+	return 0
+}
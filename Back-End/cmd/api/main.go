@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"log/slog"
@@ -16,12 +17,20 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 
 	"github.com/panbeh/otp-backend/internal/config"
+	"github.com/panbeh/otp-backend/internal/domain/apikey"
 	"github.com/panbeh/otp-backend/internal/domain/business"
+	"github.com/panbeh/otp-backend/internal/domain/delivery"
 	"github.com/panbeh/otp-backend/internal/domain/otp"
+	"github.com/panbeh/otp-backend/internal/domain/usage"
+	apiKeyRepo "github.com/panbeh/otp-backend/internal/repository/apiKeyRepo"
 	businessRepo "github.com/panbeh/otp-backend/internal/repository/businessRepo"
 	"github.com/panbeh/otp-backend/internal/repository/databases"
+	deliveryRepo "github.com/panbeh/otp-backend/internal/repository/deliveryRepo"
+	nonceRepo "github.com/panbeh/otp-backend/internal/repository/nonceRepo"
 	oTPRepo "github.com/panbeh/otp-backend/internal/repository/otpRepo"
+	usageRepo "github.com/panbeh/otp-backend/internal/repository/usageRepo"
 	"github.com/panbeh/otp-backend/internal/service"
+	"github.com/panbeh/otp-backend/internal/sms"
 	transport "github.com/panbeh/otp-backend/internal/transport/http"
 	loggerPkg "github.com/panbeh/otp-backend/pkg/logger"
 )
@@ -29,34 +38,101 @@ import (
 func main() {
 	ctx := context.Background()
 
-	err := config.Load()
+	var configOpts config.Options
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	configOpts.RegisterFlags(flags)
+	_ = flags.Parse(os.Args[1:])
+	args := flags.Args()
+
+	if len(args) > 0 && args[0] == "config" {
+		os.Exit(runConfig(configOpts, args[1:], os.Stdout))
+	}
+
+	// Migrations only need the database, so they can run before the rest of the
+	// configuration is in place.
+	if len(args) > 0 && args[0] == "migrate" {
+		os.Exit(runMigrate(ctx, configOpts, args[1:], os.Stdout))
+	}
+
+	cfg, err := config.Load(configOpts)
 	if err != nil {
 		log.Fatalf("failed to load configs: %v", err)
 	}
 
-	logger := loggerPkg.New(config.GetLogLevel())
+	logger := loggerPkg.New(cfg.LogLevel)
 	logger.Info("starting application")
 
-	postgresDB := databases.ConnectPostgres(config.GetPostgres())
-	redisDB := databases.ConnectRedisStandAlone(config.GetRedis().RedisAddr)
-
-	businessRepo := businessRepo.NewBusinessRepository(postgresDB)
-	otpRepo := oTPRepo.NewOTPRepository(redisDB)
+	postgresDB, err := databases.ConnectPostgres(ctx, cfg.Postgres)
+	if err != nil {
+		log.Fatalf("failed to connect to postgres: %v", err)
+	}
+	redisDB, err := databases.ConnectRedis(cfg.Redis)
+	if err != nil {
+		log.Fatalf("failed to connect to redis: %v", err)
+	}
 
-	businessDomainSvc := business.NewService(business.ServiceConfig{})
-	// TODO: Should we have a unique type for OTP TTL? if yes, store it in config or domain?
-	otpTTL, err := otp.NewCodeTTL(config.GetOTPTTL())
+	// Load has validated the secret already.
+	otpCodeHasher, err := otp.NewCodeHasher([]byte(cfg.OTPCodeSecret))
 	if err != nil {
-		panic("OTP TTL is not valid")
+		log.Fatalf("failed to build otp code hasher: %v", err)
 	}
 
+	businessRepo := businessRepo.NewBusinessRepository(postgresDB)
+	apiKeyRepo := apiKeyRepo.NewAPIKeyRepository(postgresDB)
+	deliveryRepo := deliveryRepo.NewDeliveryRepository(postgresDB)
+	usageCounter := usageRepo.NewUsageCounter(redisDB)
+	usageStore := usageRepo.NewUsageStore(postgresDB)
+	usageEvents := usageRepo.NewEventStore(postgresDB)
+	usageDomainSvc := usage.NewService(usage.ServiceConfig{})
+	// Metering wraps the OTP store so every verification is counted and sends past the
+	// plan's monthly quota are refused before anything is stored. Sends are counted by
+	// the sender it wraps below, once the code is handed off.
+	otpRepo := service.NewMeteredOTPRepository(
+		oTPRepo.NewOTPRepository(redisDB, cfg.OTPSendLimits, otpCodeHasher),
+		usageCounter, usageStore, usageEvents, usageDomainSvc, logger,
+	)
+
+	businessDomainSvc := business.NewService(business.ServiceConfig{})
+	// The TTL and countries are the fallbacks for businesses without their own.
 	otpDomainSvc := otp.NewService(otp.ServiceConfig{
-		TTL: otpTTL,
+		TTL:         cfg.OTPTTL,
+		MaxAttempts: cfg.OTPMaxAttempts,
+		Hasher:      otpCodeHasher,
+		Countries:   cfg.OTPDefaultCountries,
 	})
 
-	businessAppSvc := service.NewBusinessAppService(businessRepo, businessDomainSvc)
-	otpSender := service.NewLogOTPSender(logger)
-	otpAppSvc := service.NewOTPAppService(otpRepo, otpDomainSvc, otpSender)
+	apiKeySvc := service.NewAPIKeyService(apiKeyRepo, businessRepo, apikey.NewService(apikey.ServiceConfig{}))
+	smsSender, err := sms.NewSender(cfg.SMS, logger)
+	if err != nil {
+		log.Fatalf("failed to build sms sender: %v", err)
+	}
+
+	deliveryDomainSvc := delivery.NewService(delivery.ServiceConfig{})
+	deliveryTracker := sms.NewDeliveryTracker(deliveryRepo, deliveryDomainSvc)
+
+	// With async delivery the request path only enqueues; the worker talks to the gateway.
+	var otpSender sms.Sender = sms.NewTrackingSender(smsSender, deliveryTracker, logger)
+	var deliveryWorker *sms.Worker
+	if deliveryCfg := cfg.Delivery; deliveryCfg.Async {
+		// The sealer gets a key of its own, so rotating OTP_CODE_SECRET cannot strand queued codes.
+		sealer, err := sms.NewCodeSealer([]byte(deliveryCfg.SealerKey))
+		if err != nil {
+			log.Fatalf("failed to build delivery sealer: %v", err)
+		}
+		hostname, _ := os.Hostname()
+		otpSender = sms.NewDeliveryQueue(redisDB, sealer, deliveryTracker, logger)
+		deliveryWorker = sms.NewWorker(redisDB, smsSender, sealer, logger, sms.WorkerConfig{
+			Consumer:     fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+			Concurrency:  deliveryCfg.Workers,
+			MaxAttempts:  deliveryCfg.MaxAttempts,
+			BaseBackoff:  deliveryCfg.BaseBackoff,
+			MaxBackoff:   deliveryCfg.MaxBackoff,
+			ClaimIdle:    deliveryCfg.ClaimIdle,
+			DrainTimeout: deliveryCfg.DrainTimeout,
+			Tracker:      deliveryTracker,
+		})
+	}
+	otpAppSvc := service.NewOTPAppService(businessRepo, otpRepo, otpDomainSvc, otpRepo.MeterSends(otpSender), deliveryRepo, logger)
 
 	e := echo.New()
 	e.HideBanner = true
@@ -66,18 +142,32 @@ func main() {
 	e.Use(middleware.RequestID())
 	e.Use(loggerPkg.EchoMiddleware(logger))
 
-	router := transport.NewRouter(
-		logger,
-		transport.RouterDeps{
-			BusinessService: businessAppSvc,
-			OTPService:      otpAppSvc,
-			AuthResolver:    businessAppSvc,
-		},
-	)
-	router.Register(e)
+	auth := transport.Authenticator{Keys: apiKeySvc, Logger: logger}
+	if cfg.AuthSignedRequests {
+		// Signing secrets are sealed with a key of their own, so rotating OTP_CODE_SECRET
+		// cannot make them unreadable.
+		signingSealer, err := service.NewSecretSealer([]byte(cfg.AuthSigningSecretKey))
+		if err != nil {
+			log.Fatalf("failed to build signing sealer: %v", err)
+		}
+		signingSvc := service.NewRequestSigningService(businessRepo, apiKeyRepo, businessDomainSvc, signingSealer,
+			nonceRepo.NewNonceRepository(redisDB), cfg.AuthSignatureMaxSkew)
+		auth.Signatures = signingSvc
+		transport.NewSigningSecretHandler(signingSvc, auth, logger).Register(e)
+	}
+	transport.NewOTPHandler(otpAppSvc, auth, logger).Register(e)
+	transport.NewOTPSettingsHandler(service.NewOTPSettingsService(businessRepo, businessDomainSvc), auth, logger).Register(e)
+	transport.NewTokenHandler(service.NewBusinessTokenService(businessRepo, businessDomainSvc, cfg.BusinessTokenGrace), logger).Register(e)
+	transport.NewAPIKeyHandler(apiKeySvc, auth, logger).Register(e)
+	transport.NewUsageHandler(service.NewUsageReportService(usageEvents, usageDomainSvc), auth, logger).Register(e)
+	transport.NewAdminHandler(service.NewBusinessAdminService(businessRepo, businessDomainSvc, usageStore), cfg.AdminToken, logger).Register(e)
+	transport.NewSMSReportHandler(deliveryTracker, cfg.SMS.WebhookSecret, logger).Register(e)
+	transport.NewHealthHandler(map[string]transport.HealthChecker{
+		"postgres": databases.NewPostgresProbe(postgresDB),
+	}, logger).Register(e)
 
 	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%s", config.GetRestServerAddr()),
+		Addr:         fmt.Sprintf(":%s", cfg.RestAddr),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -91,6 +181,28 @@ func main() {
 		}
 	}()
 
+	workerCtx, stopWorker := context.WithCancel(ctx)
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		if deliveryWorker == nil {
+			return
+		}
+		if err := deliveryWorker.Run(workerCtx); err != nil {
+			logger.Error("delivery worker failed", slog.Any("err", err))
+		}
+	}()
+
+	usageFlusher := service.NewUsageFlusher(usageCounter, usageStore, cfg.UsageFlushInterval, logger)
+	flusherCtx, stopFlusher := context.WithCancel(ctx)
+	flusherDone := make(chan struct{})
+	go func() {
+		defer close(flusherDone)
+		if err := usageFlusher.Run(flusherCtx); err != nil {
+			logger.Error("usage flush failed", slog.Any("err", err))
+		}
+	}()
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
@@ -100,4 +212,17 @@ func main() {
 	if err := e.Shutdown(shutdownCtx); err != nil {
 		logger.Error("shutdown failed", slog.Any("err", err))
 	}
+
+	// Stop reading new jobs and let in-flight deliveries drain.
+	stopWorker()
+	<-workerDone
+
+	// The flusher writes whatever is still pending before it returns.
+	stopFlusher()
+	<-flusherDone
+
+	// Close the pool last: the worker and the final usage flush still write to it.
+	if err := postgresDB.Close(); err != nil {
+		logger.Error("closing postgres failed", slog.Any("err", err))
+	}
 }
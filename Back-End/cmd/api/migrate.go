@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/panbeh/otp-backend/internal/config"
+	"github.com/panbeh/otp-backend/internal/repository/databases"
+)
+
+const migrateUsage = `usage: api migrate <command>
+
+commands:
+  up          apply all pending migrations
+  down [N]    roll back the last N applied migrations (default 1)
+  status      list migrations and when they were applied
+`
+
+// runMigrate implements the "migrate" subcommand and returns the process exit code.
+// It reads and validates only the Postgres settings.
+func runMigrate(ctx context.Context, opts config.Options, args []string, out io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+	pgCfg, err := config.LoadPostgres(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: failed to load postgres config: %v\n", err)
+		return 1
+	}
+	db, err := databases.ConnectPostgres(ctx, pgCfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: failed to connect to postgres: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	migrator, err := databases.NewMigrator(db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return 1
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := migrator.Up(ctx)
+		printMigrations(out, "applied", done)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate up: %v\n", err)
+			return 1
+		}
+		if len(done) == 0 {
+			fmt.Fprintln(out, "schema is up to date")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				fmt.Fprint(os.Stderr, migrateUsage)
+				return 2
+			}
+		}
+		done, err := migrator.Down(ctx, steps)
+		printMigrations(out, "rolled back", done)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate down: %v\n", err)
+			return 1
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		_ = w.Flush()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate status: %v\n", err)
+			return 1
+		}
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
+
+func printMigrations(out io.Writer, verb string, ms []databases.Migration) {
+	for _, m := range ms {
+		fmt.Fprintf(out, "%s %04d_%s\n", verb, m.Version, m.Name)
+	}
+}
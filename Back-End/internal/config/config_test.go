@@ -0,0 +1,206 @@
+package config_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/config"
+)
+
+func writeFile(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+// validSecret satisfies OTP_CODE_SECRET, which has no usable default.
+func validSecret(t *testing.T) {
+	t.Helper()
+	t.Setenv("OTP_CODE_SECRET", "0123456789abcdef")
+}
+
+func settingsByKey(settings []config.Setting) map[string]config.Setting {
+	out := make(map[string]config.Setting, len(settings))
+	for _, s := range settings {
+		out[s.Key] = s
+	}
+	return out
+}
+
+func TestCheck_Precedence(t *testing.T) {
+	path := writeFile(t, `
+rest_addr: ":9000"
+log_level: debug
+otp:
+  ttl_seconds: 120
+  max_attempts: 3
+  default_country_codes: [98, 971]
+`)
+	validSecret(t)
+	t.Setenv("OTP_TTL_SECONDS", "180")
+	t.Setenv("OTP_MAX_ATTEMPTS", "4")
+
+	settings, err := config.Check(config.Options{
+		File:      path,
+		Overrides: map[string]string{"otp.max-attempts": "7"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := settingsByKey(settings)
+	for key, want := range map[string]config.Setting{
+		"REST_ADDR":                 {Value: ":9000", Source: config.SourceFile},
+		"OTP_DEFAULT_COUNTRY_CODES": {Value: "98,971", Source: config.SourceFile},
+		"OTP_TTL_SECONDS":           {Value: "180", Source: config.SourceEnv},
+		"OTP_MAX_ATTEMPTS":          {Value: "7", Source: config.SourceFlag},
+		"OTP_MAX_SENDS_PER_DAY":     {Value: "10", Source: config.SourceDefault},
+	} {
+		if s := got[key]; s.Value != want.Value || s.Source != want.Source {
+			t.Errorf("%s: expected %q from %s, got %q from %s", key, want.Value, want.Source, s.Value, s.Source)
+		}
+	}
+}
+
+func TestLoad_ReportsEveryInvalidField(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "loud")
+	t.Setenv("OTP_TTL_SECONDS", "five minutes")
+	t.Setenv("AUTH_SIGNATURE_MAX_SKEW_SECONDS", "0")
+	t.Setenv("SMS_PROVIDERS", "kavenegar")
+	path := writeFile(t, "postgres:\n  dsn: mysql://nope\nredis_mdoe: cluster\n")
+
+	_, err := config.Load(config.Options{File: path})
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+
+	keys := make(map[string]bool)
+	for _, f := range verr.Fields {
+		keys[f.Key] = true
+	}
+	for _, want := range []string{"LOG_LEVEL", "OTP_TTL_SECONDS", "AUTH_SIGNATURE_MAX_SKEW_SECONDS", "SMS", "POSTGRES", "REDIS_MDOE"} {
+		if !keys[want] {
+			t.Errorf("expected a problem reported for %s, got:\n%v", want, err)
+		}
+	}
+	if !errors.Is(err, config.ErrUnknownSetting) {
+		t.Errorf("expected the misspelled key to be reported as unknown")
+	}
+}
+
+func TestCheck_RedactsSecrets(t *testing.T) {
+	t.Setenv("OTP_CODE_SECRET", "0123456789abcdef-secret")
+	t.Setenv("POSTGRES_DSN", "postgres://app:hunter2@db:5432/panbeh")
+	t.Setenv("REDIS_ADDR", "redis://:hunter3@cache:6379/0")
+
+	settings, err := config.Check(config.Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, s := range settings {
+		if strings.Contains(s.Value, "secret") || strings.Contains(s.Value, "hunter") {
+			t.Errorf("%s leaks a secret: %q", s.Key, s.Value)
+		}
+	}
+	if got := settingsByKey(settings)["POSTGRES_DSN"].Value; !strings.Contains(got, "app:") || !strings.Contains(got, "db:5432/panbeh") {
+		t.Errorf("expected the rest of the DSN to stay readable, got %q", got)
+	}
+}
+
+func TestLoad_ReportsInvalidOTPSettings(t *testing.T) {
+	t.Setenv("OTP_TTL_SECONDS", "0")
+	t.Setenv("OTP_MAX_ATTEMPTS", "-1")
+	t.Setenv("OTP_MAX_SENDS_PER_HOUR", "-5")
+	t.Setenv("OTP_DEFAULT_COUNTRY_CODES", "98,uk")
+
+	_, err := config.Check(config.Options{})
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	keys := make(map[string]bool)
+	for _, f := range verr.Fields {
+		keys[f.Key] = true
+	}
+	for _, want := range []string{"OTP_TTL_SECONDS", "OTP_MAX_ATTEMPTS", "OTP_SEND_LIMITS", "OTP_CODE_SECRET", "OTP_DEFAULT_COUNTRY_CODES"} {
+		if !keys[want] {
+			t.Errorf("expected a problem reported for %s, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoad_SealingKeys(t *testing.T) {
+	otpSecret := "0123456789abcdef0123456789abcdef-otp"
+	t.Setenv("OTP_CODE_SECRET", otpSecret)
+	t.Setenv("AUTH_SIGNED_REQUESTS", "true")
+	t.Setenv("OTP_DELIVERY_ASYNC", "true")
+
+	for _, key := range []string{"", "too-short", otpSecret} {
+		t.Setenv("AUTH_SIGNING_SECRET_KEY", key)
+		t.Setenv("OTP_DELIVERY_SEALER_KEY", key)
+		_, err := config.Load(config.Options{})
+		for _, name := range []string{"AUTH_SIGNING_SECRET_KEY", "OTP_DELIVERY_SEALER_KEY"} {
+			if !strings.Contains(fmt.Sprint(err), name) {
+				t.Errorf("expected %s=%q to be rejected, got %v", name, key, err)
+			}
+		}
+	}
+
+	t.Setenv("AUTH_SIGNING_SECRET_KEY", "0123456789abcdef0123456789abcdef-signing")
+	t.Setenv("OTP_DELIVERY_SEALER_KEY", "0123456789abcdef0123456789abcdef-sealer")
+	c, err := config.Load(config.Options{})
+	if err != nil {
+		t.Fatalf("expected valid keys to load, got %v", err)
+	}
+	if c.AuthSigningSecretKey == "" || c.Delivery.SealerKey == "" {
+		t.Fatalf("expected the keys to be kept")
+	}
+}
+
+func TestLoad_IndependentConfigs(t *testing.T) {
+	validSecret(t)
+	a, err := config.Load(config.Options{Overrides: map[string]string{"OTP_TTL_SECONDS": "60"}})
+	if err != nil {
+		t.Fatalf("load a: %v", err)
+	}
+	b, err := config.Load(config.Options{Overrides: map[string]string{"OTP_TTL_SECONDS": "600"}})
+	if err != nil {
+		t.Fatalf("load b: %v", err)
+	}
+	if time.Duration(a.OTPTTL) != time.Minute || time.Duration(b.OTPTTL) != 10*time.Minute {
+		t.Fatalf("expected each Load to keep its own values, got %v and %v", a.OTPTTL, b.OTPTTL)
+	}
+}
+
+func TestLoadPostgres_IgnoresOtherSettings(t *testing.T) {
+	// No OTP_CODE_SECRET and a broken OTP setting would fail Load, but not the
+	// Postgres-only load migrations use.
+	t.Setenv("OTP_TTL_SECONDS", "five minutes")
+	path := writeFile(t, "postgres:\n  dsn: postgres://u:p@db:5432/app\nsms:\n  providers: [kavenegar]\n")
+
+	if _, err := config.Load(config.Options{File: path}); err == nil {
+		t.Fatalf("expected the full load to fail")
+	}
+	pg, err := config.LoadPostgres(config.Options{File: path})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pg.PostgresDSN != "postgres://u:p@db:5432/app" {
+		t.Fatalf("expected the file's dsn, got %q", pg.PostgresDSN)
+	}
+
+	path = writeFile(t, "postgres:\n  dsn: mysql://nope\n")
+	var verr *config.ValidationError
+	if _, err := config.LoadPostgres(config.Options{File: path}); !errors.As(err, &verr) {
+		t.Fatalf("expected a ValidationError for a bad dsn, got %v", err)
+	}
+}
@@ -5,26 +5,22 @@ import (
 	"fmt"
 	"net"
 	"net/url"
+	"os"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/otp"
 )
 
 type LogLevel string
 
-func NewLogLevel(level string) LogLevel {
-	level = strings.ToUpper(level)
-	switch level {
-	case "DEBUG":
-		return LogLevelDebug
-	case "INFO":
-		return LogLevelInfo
-	case "WARN":
-		return LogLevelWarn
-	case "ERROR":
-		return LogLevelError
+func NewLogLevel(level string) (LogLevel, error) {
+	switch l := LogLevel(strings.ToUpper(strings.TrimSpace(level))); l {
+	case LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError:
+		return l, nil
 	}
-	panic(fmt.Sprintf("Invalid log level in config: %s", level))
+	return "", fmt.Errorf("invalid log level: %s", level)
 }
 
 const (
@@ -108,10 +104,18 @@ func NewPostgresDSN(dsn string) (PostgresDSN, error) {
 	return validateKeyValueDSN(dsn)
 }
 
-func NewPostgresConfig(postgresDSN string, maxOpenConns int, maxIdleConns int, connMaxLifetime time.Duration) Postgres {
+// NewPostgresConfig reports every invalid setting at once, joined into one error.
+func NewPostgresConfig(postgresDSN string, maxOpenConns int, maxIdleConns int, connMaxLifetime time.Duration, connectTimeout time.Duration) (Postgres, error) {
+	var errs []error
 	dsn, err := NewPostgresDSN(postgresDSN)
 	if err != nil {
-		panic(err)
+		errs = append(errs, fmt.Errorf("postgres dsn: %w", err))
+	}
+	if connectTimeout <= 0 {
+		errs = append(errs, errors.New("postgres connect timeout must be positive"))
+	}
+	if len(errs) > 0 {
+		return Postgres{}, errors.Join(errs...)
 	}
 
 	return Postgres{
@@ -119,7 +123,8 @@ func NewPostgresConfig(postgresDSN string, maxOpenConns int, maxIdleConns int, c
 		PostgresMaxOpenConns:    maxOpenConns,
 		PostgresMaxIdleConns:    maxIdleConns,
 		PostgresConnMaxLifetime: connMaxLifetime,
-	}
+		PostgresConnectTimeout:  connectTimeout,
+	}, nil
 }
 
 type RedisAddr string
@@ -201,15 +206,325 @@ func NewRedisAddr(addr string) (RedisAddr, error) {
 	return validateRedisHostPort(addr)
 }
 
-func NewRedisConfig(addr string) Redis {
-	redisAddr, err := NewRedisAddr(addr)
+type RedisMode string
+
+const (
+	RedisModeStandalone RedisMode = "standalone"
+	RedisModeSentinel   RedisMode = "sentinel"
+	RedisModeCluster    RedisMode = "cluster"
+)
+
+func NewRedisMode(mode string) (RedisMode, error) {
+	switch m := RedisMode(strings.ToLower(strings.TrimSpace(mode))); m {
+	case RedisModeStandalone, RedisModeSentinel, RedisModeCluster:
+		return m, nil
+	}
+	return "", fmt.Errorf("invalid redis mode: %s", mode)
+}
+
+// RedisNodeAddr is a plain host:port, as sentinels and cluster nodes are listed.
+type RedisNodeAddr string
+
+func NewRedisNodeAddr(addr string) (RedisNodeAddr, error) {
+	host, port, err := net.SplitHostPort(strings.TrimSpace(addr))
 	if err != nil {
-		panic(err)
+		return "", errors.New("invalid redis node address, expected host:port: " + addr)
+	}
+	if host == "" {
+		return "", errors.New("host is empty: " + addr)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+		return "", errors.New("port must be a number between 1 and 65535: " + addr)
+	}
+	return RedisNodeAddr(net.JoinHostPort(host, port)), nil
+}
+
+// NewRedisNodeAddrs parses a comma-separated host:port list; at least one is required.
+func NewRedisNodeAddrs(addrs string) ([]RedisNodeAddr, error) {
+	var out []RedisNodeAddr
+	seen := make(map[RedisNodeAddr]bool)
+	for _, raw := range strings.Split(addrs, ",") {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		a, err := NewRedisNodeAddr(raw)
+		if err != nil {
+			return nil, err
+		}
+		if seen[a] {
+			return nil, fmt.Errorf("duplicate redis node address: %s", a)
+		}
+		seen[a] = true
+		out = append(out, a)
 	}
+	if len(out) == 0 {
+		return nil, errors.New("at least one redis node address is required")
+	}
+	return out, nil
+}
 
-	return Redis{
-		RedisAddr: redisAddr,
+type RedisMasterName string
+
+func NewRedisMasterName(name string) (RedisMasterName, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("redis sentinel master name is empty")
+	}
+	if strings.ContainsAny(name, " \t\r\n") {
+		return "", errors.New("redis sentinel master name must not contain whitespace")
 	}
+	return RedisMasterName(name), nil
+}
+
+// RedisConnection holds the settings shared by every Redis mode. Zero timeouts and
+// pool size leave the go-redis defaults in place.
+type RedisConnection struct {
+	Username string
+	Password string
+	DB       int
+	TLS      RedisTLS
+
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	PoolSize     int
+}
+
+// RedisTLS enables TLS. CAFile replaces the system roots for verifying the server;
+// CertFile and KeyFile, which go together, present a client certificate.
+type RedisTLS struct {
+	Enabled  bool
+	CAFile   string
+	CertFile string
+	KeyFile  string
+}
+
+func NewRedisTLS(enabled bool, caFile, certFile, keyFile string) (RedisTLS, error) {
+	t := RedisTLS{Enabled: enabled, CAFile: caFile, CertFile: certFile, KeyFile: keyFile}
+	if !enabled {
+		if caFile != "" || certFile != "" || keyFile != "" {
+			return RedisTLS{}, errors.New("redis tls files are set but tls is disabled")
+		}
+		return t, nil
+	}
+	if (certFile == "") != (keyFile == "") {
+		return RedisTLS{}, errors.New("redis tls cert and key files must be set together")
+	}
+	for _, f := range []string{caFile, certFile, keyFile} {
+		if f == "" {
+			continue
+		}
+		if _, err := os.Stat(f); err != nil {
+			return RedisTLS{}, fmt.Errorf("redis tls file: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// NewRedisConfig validates only the settings the chosen mode uses: the address for
+// standalone, the master name and sentinel addresses for sentinel, and the seed nodes
+// for cluster.
+//
+// A standalone address may be a redis:// or rediss:// URL or a bare host:port. A URL's
+// credentials, DB index and rediss scheme fill in what conn leaves unset; setting the
+// same thing differently in both places is an error. Every invalid setting is
+// reported, joined into one error.
+func NewRedisConfig(mode string, addr string, sentinelMaster string, sentinelAddrs string, clusterAddrs string, conn RedisConnection) (Redis, error) {
+	var errs []error
+	redisMode, err := NewRedisMode(mode)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if conn.DB < 0 {
+		errs = append(errs, errors.New("redis db index must not be negative"))
+	}
+	if conn.DialTimeout < 0 || conn.ReadTimeout < 0 || conn.WriteTimeout < 0 || conn.PoolSize < 0 {
+		errs = append(errs, errors.New("redis timeouts and pool size must not be negative"))
+	}
+
+	cfg := Redis{Mode: redisMode}
+	switch redisMode {
+	case RedisModeStandalone:
+		if cfg.RedisAddr, err = NewRedisAddr(addr); err != nil {
+			errs = append(errs, err)
+		} else if cfg.Addr, conn, err = mergeRedisURL(cfg.RedisAddr, conn); err != nil {
+			errs = append(errs, err)
+		}
+	case RedisModeSentinel:
+		if cfg.SentinelMaster, err = NewRedisMasterName(sentinelMaster); err != nil {
+			errs = append(errs, err)
+		}
+		if cfg.SentinelAddrs, err = NewRedisNodeAddrs(sentinelAddrs); err != nil {
+			errs = append(errs, fmt.Errorf("redis sentinel addrs: %w", err))
+		}
+	case RedisModeCluster:
+		if cfg.ClusterAddrs, err = NewRedisNodeAddrs(clusterAddrs); err != nil {
+			errs = append(errs, fmt.Errorf("redis cluster addrs: %w", err))
+		}
+		if conn.DB != 0 {
+			errs = append(errs, errors.New("redis cluster only has db 0"))
+		}
+	}
+	if len(errs) > 0 {
+		return Redis{}, errors.Join(errs...)
+	}
+	cfg.RedisConnection = conn
+	return cfg, nil
+}
+
+// mergeRedisURL splits a standalone address into host:port and the connection
+// settings a URL may carry.
+func mergeRedisURL(addr RedisAddr, conn RedisConnection) (RedisNodeAddr, RedisConnection, error) {
+	raw := string(addr)
+	if !strings.HasPrefix(raw, "redis://") && !strings.HasPrefix(raw, "rediss://") {
+		node, err := NewRedisNodeAddr(raw)
+		return node, conn, err
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", conn, err
+	}
+	node, err := NewRedisNodeAddr(u.Host)
+	if err != nil {
+		return "", conn, err
+	}
+
+	if u.User != nil {
+		if conn.Username, err = mergeRedisSetting("username", conn.Username, u.User.Username()); err != nil {
+			return "", conn, err
+		}
+		password, _ := u.User.Password()
+		if conn.Password, err = mergeRedisSetting("password", conn.Password, password); err != nil {
+			return "", conn, err
+		}
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		n, _ := strconv.Atoi(db) // validated by NewRedisAddr
+		if conn.DB != 0 && conn.DB != n {
+			return "", conn, errors.New("redis db is set differently in REDIS_ADDR and REDIS_DB")
+		}
+		conn.DB = n
+	}
+	if u.Scheme == "rediss" {
+		conn.TLS.Enabled = true
+	}
+	return node, conn, nil
+}
+
+func mergeRedisSetting(name, explicit, fromURL string) (string, error) {
+	if explicit != "" && fromURL != "" && explicit != fromURL {
+		return "", fmt.Errorf("redis %s is set differently in REDIS_ADDR and its own setting", name)
+	}
+	if explicit != "" {
+		return explicit, nil
+	}
+	return fromURL, nil
+}
+
+type SMSProvider string
+
+const (
+	SMSProviderLog       SMSProvider = "log"
+	SMSProviderKavenegar SMSProvider = "kavenegar"
+	SMSProviderSMSIR     SMSProvider = "smsir"
+	SMSProviderHTTP      SMSProvider = "http"
+)
+
+func NewSMSProvider(provider string) (SMSProvider, error) {
+	switch p := SMSProvider(strings.ToLower(strings.TrimSpace(provider))); p {
+	case SMSProviderLog, SMSProviderKavenegar, SMSProviderSMSIR, SMSProviderHTTP:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid sms provider: %s", provider)
+}
+
+// ParseHeaders parses "Key: Value; Other: Value" into a header map.
+func ParseHeaders(raw string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, part := range strings.Split(raw, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(part, ":")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, errors.New("invalid header: " + part)
+		}
+		headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return headers, nil
+}
+
+// NewSMSProviders parses a comma-separated provider list; order is delivery priority.
+func NewSMSProviders(providers string) ([]SMSProvider, error) {
+	var out []SMSProvider
+	seen := make(map[SMSProvider]bool)
+	for _, raw := range strings.Split(providers, ",") {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		p, err := NewSMSProvider(raw)
+		if err != nil {
+			return nil, err
+		}
+		if seen[p] {
+			return nil, fmt.Errorf("duplicate sms provider: %s", p)
+		}
+		seen[p] = true
+		out = append(out, p)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("at least one sms provider is required")
+	}
+	return out, nil
+}
+
+// NewSMSConfig checks the settings of every selected provider and reports every
+// invalid one, joined into one error.
+func NewSMSConfig(providers string, timeout time.Duration, breakerFailureThreshold int, breakerOpenTimeout time.Duration, kavenegar Kavenegar, smsIR SMSIR, smsHTTP SMSHTTP) (SMS, error) {
+	var errs []error
+	ps, err := NewSMSProviders(providers)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if timeout <= 0 {
+		errs = append(errs, errors.New("sms timeout must be positive"))
+	}
+	if breakerFailureThreshold <= 0 || breakerOpenTimeout <= 0 {
+		errs = append(errs, errors.New("sms circuit breaker settings must be positive"))
+	}
+
+	for _, p := range ps {
+		switch p {
+		case SMSProviderKavenegar:
+			if kavenegar.APIKey == "" || kavenegar.Template == "" {
+				errs = append(errs, errors.New("kavenegar api key and template are required"))
+			}
+		case SMSProviderSMSIR:
+			if smsIR.APIKey == "" || smsIR.TemplateID <= 0 {
+				errs = append(errs, errors.New("sms.ir api key and template id are required"))
+			}
+		case SMSProviderHTTP:
+			u, err := url.Parse(smsHTTP.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errs = append(errs, errors.New("sms http url must be an absolute http(s) url"))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return SMS{}, errors.Join(errs...)
+	}
+
+	return SMS{
+		Providers:               ps,
+		Timeout:                 timeout,
+		BreakerFailureThreshold: breakerFailureThreshold,
+		BreakerOpenTimeout:      breakerOpenTimeout,
+		Kavenegar:               kavenegar,
+		SMSIR:                   smsIR,
+		HTTP:                    smsHTTP,
+	}, nil
 }
 
 type Config struct {
@@ -220,7 +535,35 @@ type Config struct {
 	RestAddr string
 	Postgres
 	Redis
-	OTPTTL time.Duration
+	OTPTTL         otp.CodeTTL
+	OTPMaxAttempts otp.MaxAttempts
+	OTPSendLimits  otp.SendLimits
+
+	// OTPCodeSecret keys the HMAC used to store OTP codes at rest. Load has already
+	// checked it is long enough for otp.NewCodeHasher.
+	OTPCodeSecret string
+	// OTPDefaultCountries are the calling codes businesses may send to unless they have their own allowlist.
+	OTPDefaultCountries otp.CountryAllowlist
+
+	// BusinessTokenGrace is how long a business token keeps working after it is rotated.
+	BusinessTokenGrace time.Duration
+
+	// AuthSignedRequests lets businesses authenticate with HMAC-signed requests instead
+	// of bearer keys; AuthSignatureMaxSkew is how far a signature timestamp may drift.
+	AuthSignedRequests   bool
+	AuthSignatureMaxSkew time.Duration
+	// AuthSigningSecretKey encrypts the businesses' signing secrets at rest. It is only
+	// required with AuthSignedRequests.
+	AuthSigningSecretKey string
+
+	// AdminToken guards the operator routes under /admin; empty disables them.
+	AdminToken string
+
+	// UsageFlushInterval is how often the Redis usage counters are copied to Postgres.
+	UsageFlushInterval time.Duration
+
+	SMS      SMS
+	Delivery Delivery
 }
 
 type Postgres struct {
@@ -228,8 +571,75 @@ type Postgres struct {
 	PostgresMaxOpenConns    int
 	PostgresMaxIdleConns    int
 	PostgresConnMaxLifetime time.Duration
+	// PostgresConnectTimeout is how long startup keeps retrying before giving up.
+	PostgresConnectTimeout time.Duration
 }
 
 type Redis struct {
+	Mode RedisMode
+	// RedisAddr is the standalone server as configured; Addr is its host:port, with
+	// anything else the URL carried moved into RedisConnection.
 	RedisAddr RedisAddr
+	Addr      RedisNodeAddr
+	// SentinelMaster names the monitored master that SentinelAddrs are asked about.
+	SentinelMaster RedisMasterName
+	SentinelAddrs  []RedisNodeAddr
+	// ClusterAddrs seeds cluster discovery; the rest of the nodes are found from them.
+	ClusterAddrs []RedisNodeAddr
+
+	RedisConnection
+}
+
+type SMS struct {
+	// Providers in delivery priority order.
+	Providers []SMSProvider
+	Timeout   time.Duration
+
+	BreakerFailureThreshold int
+	BreakerOpenTimeout      time.Duration
+
+	// WebhookSecret authenticates delivery-report callbacks, either as the HMAC key of
+	// their X-Webhook-Signature or sent as is in X-Webhook-Secret.
+	WebhookSecret string
+
+	Kavenegar Kavenegar
+	SMSIR     SMSIR
+	HTTP      SMSHTTP
+}
+
+type Kavenegar struct {
+	BaseURL  string
+	APIKey   string
+	Template string
+}
+
+type SMSIR struct {
+	BaseURL    string
+	APIKey     string
+	TemplateID int
+	CodeParam  string
+}
+
+// SMSHTTP configures the generic gateway adapter. URL and BodyTemplate are Go
+// text/templates receiving {{.Phone}} and {{.Code}} raw, and {{.PhoneQuery}} and
+// {{.CodeQuery}} query-escaped for use in the URL.
+type SMSHTTP struct {
+	URL          string
+	Method       string
+	Headers      map[string]string
+	ContentType  string
+	BodyTemplate string
+}
+
+// Delivery configures the asynchronous OTP delivery queue.
+type Delivery struct {
+	Async        bool
+	Workers      int
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	ClaimIdle    time.Duration
+	DrainTimeout time.Duration
+	// SealerKey encrypts codes while they wait in the queue; required with Async.
+	SealerKey string
 }
@@ -0,0 +1,315 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnknownSetting is reported for config file or command-line keys Load never reads,
+// which are almost always typos.
+var ErrUnknownSetting = errors.New("unknown setting")
+
+// Options picks the layers Load reads on top of the built-in defaults. From lowest to
+// highest precedence: defaults, File, environment variables, Overrides. An empty
+// value at any layer counts as unset.
+type Options struct {
+	// File is a YAML config file. Empty falls back to $CONFIG_FILE, and to no file when
+	// that is unset too.
+	File string
+	// Overrides are KEY=VALUE settings given on the command line.
+	Overrides map[string]string
+}
+
+// RegisterFlags adds -config and the repeatable -set KEY=VALUE to fs.
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.File, "config", "", "YAML config file (default $CONFIG_FILE)")
+	fs.Var(overrideFlag{o}, "set", "override a setting as KEY=VALUE; repeatable")
+}
+
+type overrideFlag struct{ opts *Options }
+
+func (f overrideFlag) String() string { return "" }
+
+func (f overrideFlag) Set(v string) error {
+	key, value, ok := strings.Cut(v, "=")
+	if key = normalizeKey(key); !ok || key == "" {
+		return errors.New("expected KEY=VALUE")
+	}
+	if f.opts.Overrides == nil {
+		f.opts.Overrides = make(map[string]string)
+	}
+	f.opts.Overrides[key] = value
+	return nil
+}
+
+// Source is the layer a setting's value came from.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Setting is one resolved value as `config check` shows it, with secrets redacted.
+type Setting struct {
+	Key    string
+	Value  string
+	Source Source
+}
+
+// FieldError is one invalid setting, named by its key or, for checks spanning several
+// settings, by the section they belong to.
+type FieldError struct {
+	Key string
+	Err error
+}
+
+func (e *FieldError) Error() string { return e.Key + ": " + e.Err.Error() }
+
+func (e *FieldError) Unwrap() error { return e.Err }
+
+// ValidationError lists every invalid setting Load found.
+type ValidationError struct {
+	Fields []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration (%d problems):", len(e.Fields))
+	for _, f := range e.Fields {
+		b.WriteString("\n  ")
+		b.WriteString(f.Error())
+	}
+	return b.String()
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Fields))
+	for i, f := range e.Fields {
+		errs[i] = f
+	}
+	return errs
+}
+
+// loader resolves keys through the layers and collects every problem instead of
+// stopping at the first. Keys are the environment variable names.
+type loader struct {
+	file     map[string]string
+	flags    map[string]string
+	read     map[string]bool
+	settings []Setting
+	errs     []*FieldError
+}
+
+func newLoader(opts Options) (*loader, error) {
+	l := &loader{flags: make(map[string]string), read: make(map[string]bool)}
+	path := opts.File
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		file, err := readFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("config file %s: %w", path, err)
+		}
+		l.file = file
+	}
+	for k, v := range opts.Overrides {
+		l.flags[normalizeKey(k)] = v
+	}
+	return l, nil
+}
+
+func (l *loader) lookup(key, def string, redact func(string) string) string {
+	l.read[key] = true
+	v, src := def, SourceDefault
+	if fv := l.file[key]; fv != "" {
+		v, src = fv, SourceFile
+	}
+	if ev := os.Getenv(key); ev != "" {
+		v, src = ev, SourceEnv
+	}
+	if fl := l.flags[key]; fl != "" {
+		v, src = fl, SourceFlag
+	}
+	shown := v
+	if redact != nil && v != "" {
+		shown = redact(v)
+	}
+	l.settings = append(l.settings, Setting{Key: key, Value: shown, Source: src})
+	return v
+}
+
+func (l *loader) str(key, def string) string {
+	return l.lookup(key, def, nil)
+}
+
+// secret is shown as redacted in full.
+func (l *loader) secret(key, def string) string {
+	return l.lookup(key, def, func(string) string { return redacted })
+}
+
+// dsn is a URL or key=value connection string whose password is redacted.
+func (l *loader) dsn(key, def string) string {
+	return l.lookup(key, def, redactCredentials)
+}
+
+// int falls back to the default on a parse error so the bad value is reported once,
+// not again by whatever checks the number afterwards.
+func (l *loader) int(key, def string) int {
+	v := l.str(key, def)
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		l.fail(key, fmt.Errorf("not an integer: %q", v))
+		n, _ = strconv.Atoi(def)
+	}
+	return n
+}
+
+func (l *loader) seconds(key, def string) time.Duration {
+	return time.Duration(l.int(key, def)) * time.Second
+}
+
+func (l *loader) bool(key, def string) bool {
+	v := l.str(key, def)
+	b, err := strconv.ParseBool(strings.TrimSpace(v))
+	if err != nil {
+		l.fail(key, fmt.Errorf("not a boolean: %q", v))
+		b, _ = strconv.ParseBool(def)
+	}
+	return b
+}
+
+func (l *loader) list(key, def string) []string {
+	return parseList(l.str(key, def))
+}
+
+// check records err, if any, against key. Joined errors become one entry each.
+func (l *loader) check(key string, err error) {
+	if err != nil {
+		l.fail(key, err)
+	}
+}
+
+func (l *loader) fail(key string, err error) {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			l.fail(key, e)
+		}
+		return
+	}
+	l.errs = append(l.errs, &FieldError{Key: key, Err: err})
+}
+
+// finish reports file and command-line keys nothing read, then every problem found.
+func (l *loader) finish() error {
+	for _, layer := range []struct {
+		name   string
+		values map[string]string
+	}{{"config file", l.file}, {"command line", l.flags}} {
+		var unknown []string
+		for k := range layer.values {
+			if !l.read[k] {
+				unknown = append(unknown, k)
+			}
+		}
+		sort.Strings(unknown)
+		for _, k := range unknown {
+			l.fail(k, fmt.Errorf("%w in %s", ErrUnknownSetting, layer.name))
+		}
+	}
+	if len(l.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: l.errs}
+}
+
+const redacted = "REDACTED"
+
+func redactCredentials(v string) string {
+	if u, err := url.Parse(v); err == nil && u.Scheme != "" {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		}
+		return u.String()
+	}
+	fields := strings.Fields(v)
+	for i, f := range fields {
+		if k, _, ok := strings.Cut(f, "="); ok && k == "password" {
+			fields[i] = "password=" + redacted
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// readFile flattens a YAML document into keys named like the environment variables:
+// nested sections join with "_" (postgres: {dsn: ...} is POSTGRES_DSN) and lists of
+// plain values join with ",".
+func readFile(path string) (map[string]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	out := make(map[string]string)
+	for k, v := range doc {
+		if err := flatten(normalizeKey(k), v, out); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func flatten(key string, v any, out map[string]string) error {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if err := flatten(key+"_"+normalizeKey(k), child, out); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			switch item.(type) {
+			case map[string]any, map[any]any, []any:
+				return fmt.Errorf("%s: list items must be plain values", key)
+			}
+			items = append(items, fmt.Sprint(item))
+		}
+		return set(out, key, strings.Join(items, ","))
+	case map[any]any:
+		return fmt.Errorf("%s: keys must be strings", key)
+	case nil:
+		return set(out, key, "")
+	default:
+		return set(out, key, fmt.Sprint(v))
+	}
+}
+
+func set(out map[string]string, key, value string) error {
+	if _, dup := out[key]; dup {
+		return fmt.Errorf("%s is set more than once", key)
+	}
+	out[key] = value
+	return nil
+}
+
+func normalizeKey(k string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(strings.TrimSpace(k)))
+}
@@ -0,0 +1,61 @@
+package apikey
+
+import "time"
+
+type Scope string
+
+const (
+	ScopeOTPSend    Scope = "otp:send"
+	ScopeOTPVerify  Scope = "otp:verify"
+	ScopeAdminRead  Scope = "admin:read"
+	ScopeAdminWrite Scope = "admin:write"
+)
+
+// AllScopes is every scope a key can hold. The legacy business token is treated as
+// a key with all of them.
+var AllScopes = []Scope{ScopeOTPSend, ScopeOTPVerify, ScopeAdminRead, ScopeAdminWrite}
+
+// OTPScopes are the scopes that send and verify codes, without any admin access.
+var OTPScopes = []Scope{ScopeOTPSend, ScopeOTPVerify}
+
+// APIKey is one named credential of a business. A business can hold many, e.g. one
+// per integration, each limited to the scopes that integration needs.
+type APIKey struct {
+	ID         string
+	BusinessID string
+	Name       string
+
+	// Key is the raw secret. It is only set when the key is created so it can be shown
+	// once; storage keeps KeyHash and the non-secret KeyPrefix.
+	Key       string
+	KeyHash   string
+	KeyPrefix string
+
+	Scopes     []Scope
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+func (k APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+func (k APIKey) HasScope(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScopes reports whether k holds every one of scopes.
+func (k APIKey) HasScopes(scopes []Scope) bool {
+	for _, s := range scopes {
+		if !k.HasScope(s) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,18 @@
+package apikey
+
+import "errors"
+
+var (
+	ErrInvalidBusiness = errors.New("apikey: invalid business id")
+	ErrInvalidName     = errors.New("apikey: invalid name")
+	ErrInvalidScope    = errors.New("apikey: invalid scope")
+	ErrInvalidKey      = errors.New("apikey: invalid key")
+	ErrNotFound        = errors.New("apikey: not found")
+	ErrRevoked         = errors.New("apikey: key revoked")
+	ErrScopeDenied     = errors.New("apikey: scope not granted")
+
+	// Signed-request failures.
+	ErrInvalidSignature = errors.New("apikey: invalid request signature")
+	ErrStaleSignature   = errors.New("apikey: request timestamp outside allowed skew")
+	ErrReplayedNonce    = errors.New("apikey: request nonce already used")
+)
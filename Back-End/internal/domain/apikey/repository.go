@@ -0,0 +1,19 @@
+package apikey
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	Create(ctx context.Context, k APIKey) error
+	// GetByKey looks the key up by business.HashToken(key), including revoked keys.
+	// The returned Key is empty.
+	GetByKey(ctx context.Context, key string) (APIKey, error)
+	// GetByID only finds keys owned by businessID, including revoked ones.
+	GetByID(ctx context.Context, businessID, id string) (APIKey, error)
+	ListByBusiness(ctx context.Context, businessID string) ([]APIKey, error)
+	// Revoke only affects keys owned by businessID and not already revoked.
+	Revoke(ctx context.Context, businessID, id string, at time.Time) error
+	TouchLastUsed(ctx context.Context, id string, at time.Time) error
+}
@@ -0,0 +1,84 @@
+package apikey_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/apikey"
+	"github.com/panbeh/otp-backend/internal/domain/business"
+)
+
+func newTestService(now time.Time) *apikey.Service {
+	return apikey.NewService(apikey.ServiceConfig{
+		Now:    func() time.Time { return now },
+		IDGen:  func() (string, error) { return "key1", nil },
+		KeyGen: func() (string, error) { return "0123456789abcdef", nil },
+	})
+}
+
+func TestService_NewKey(t *testing.T) {
+	now := time.Unix(10, 0)
+	svc := newTestService(now)
+
+	k, err := svc.NewKey("b1", " mobile backend ", []string{"otp:send", "OTP:VERIFY", "otp:send"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if k.ID != "key1" || k.BusinessID != "b1" || k.Name != "mobile backend" || !k.CreatedAt.Equal(now) {
+		t.Fatalf("unexpected key: %#v", k)
+	}
+	if k.Key != "0123456789abcdef" || k.KeyHash != business.HashToken(k.Key) || k.KeyPrefix != "01234567" {
+		t.Fatalf("unexpected key material: %#v", k)
+	}
+	if len(k.Scopes) != 2 || !k.HasScope(apikey.ScopeOTPSend) || !k.HasScope(apikey.ScopeOTPVerify) {
+		t.Fatalf("unexpected scopes: %v", k.Scopes)
+	}
+}
+
+func TestService_NewKey_Validates(t *testing.T) {
+	svc := newTestService(time.Unix(10, 0))
+
+	if _, err := svc.NewKey("", "web", []string{"otp:send"}); err != apikey.ErrInvalidBusiness {
+		t.Fatalf("expected ErrInvalidBusiness, got %v", err)
+	}
+	if _, err := svc.NewKey("b1", "  ", []string{"otp:send"}); err != apikey.ErrInvalidName {
+		t.Fatalf("expected ErrInvalidName, got %v", err)
+	}
+	if _, err := svc.NewKey("b1", "web", nil); err != apikey.ErrInvalidScope {
+		t.Fatalf("expected ErrInvalidScope for no scopes, got %v", err)
+	}
+	if _, err := svc.NewKey("b1", "web", []string{"otp:*"}); err != apikey.ErrInvalidScope {
+		t.Fatalf("expected ErrInvalidScope for unknown scope, got %v", err)
+	}
+}
+
+func TestService_NewKeyFor(t *testing.T) {
+	svc := newTestService(time.Unix(10, 0))
+	issuer := apikey.APIKey{ID: "k0", BusinessID: "b1", Scopes: []apikey.Scope{apikey.ScopeAdminWrite, apikey.ScopeOTPSend}}
+
+	k, err := svc.NewKeyFor(issuer, "web", []string{"otp:send"})
+	if err != nil || k.BusinessID != "b1" {
+		t.Fatalf("expected a key for the issuer's business, got %#v err=%v", k, err)
+	}
+	if _, err := svc.NewKeyFor(issuer, "web", []string{"otp:send", "otp:verify"}); err != apikey.ErrScopeDenied {
+		t.Fatalf("expected ErrScopeDenied for a scope the issuer lacks, got %v", err)
+	}
+}
+
+func TestService_Authorize(t *testing.T) {
+	svc := newTestService(time.Unix(10, 0))
+	k := apikey.APIKey{Scopes: []apikey.Scope{apikey.ScopeOTPSend}}
+
+	if err := svc.Authorize(k, apikey.ScopeOTPSend); err != nil {
+		t.Fatalf("expected granted scope to pass, got %v", err)
+	}
+	if err := svc.Authorize(k, apikey.ScopeAdminRead); err != apikey.ErrScopeDenied {
+		t.Fatalf("expected ErrScopeDenied, got %v", err)
+	}
+
+	revokedAt := time.Unix(20, 0)
+	k.RevokedAt = &revokedAt
+	if err := svc.Authorize(k, apikey.ScopeOTPSend); err != apikey.ErrRevoked {
+		t.Fatalf("expected ErrRevoked, got %v", err)
+	}
+}
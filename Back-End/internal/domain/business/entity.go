@@ -2,9 +2,77 @@ package business
 
 import "time"
 
+type Status string
+
+const (
+	StatusActive    Status = "active"
+	StatusSuspended Status = "suspended"
+	// StatusDeleted is a soft delete: the row stays for history but the business is gone
+	// for every other purpose. It is terminal.
+	StatusDeleted Status = "deleted"
+)
+
+func NewStatus(s string) (Status, error) {
+	switch st := Status(s); st {
+	case StatusActive, StatusSuspended, StatusDeleted:
+		return st, nil
+	}
+	return "", ErrInvalidStatus
+}
+
 type Business struct {
-	ID        string
-	Name      string
-	Token     string
-	CreatedAt time.Time
+	ID     string
+	Name   string
+	Status Status
+	// Token is the raw API token. It is only set when a token is issued (creation or
+	// rotation) so it can be shown once; storage keeps TokenHash and TokenPrefix.
+	Token string
+	// TokenHash is HashToken(Token). TokenPrefix is the non-secret start of the token
+	// used to tell tokens apart in listings and logs.
+	TokenHash   string
+	TokenPrefix string
+	CreatedAt   time.Time
+	DeletedAt   *time.Time
+
+	// PreviousTokenHash is the hash of the token replaced by the last rotation. That token
+	// keeps authenticating until PreviousTokenExpiresAt so integrations can switch over
+	// without downtime.
+	PreviousTokenHash      string
+	PreviousTokenExpiresAt time.Time
+
+	// AllowedCountries lists the country calling codes (e.g. "98", "971") the business
+	// may send OTPs to. Empty means the deployment default.
+	AllowedCountries []string
+
+	// CodeLength and CodeCharset ("numeric" or "alphanumeric") shape the OTP codes sent
+	// for this business. Zero values mean the deployment default (6 digits).
+	CodeLength  int
+	CodeCharset string
+
+	// OTPTTL overrides how long this business's codes stay valid. Zero means the
+	// deployment default (OTP_TTL).
+	OTPTTL time.Duration
+
+	// PlanID is the billing plan whose monthly quota applies. Empty means unmetered.
+	PlanID string
+}
+
+// SigningSecret is a business's request-signing secret, sealed for storage, and the
+// API key whose scopes signed requests act with. An empty KeyID marks a secret issued
+// before secrets were tied to keys.
+type SigningSecret struct {
+	KeyID  string
+	Sealed string
+}
+
+// CheckActive reports whether the business may use the API: ErrSuspended while it is
+// suspended and ErrNotFound once it is deleted.
+func (b Business) CheckActive() error {
+	switch b.Status {
+	case StatusSuspended:
+		return ErrSuspended
+	case StatusDeleted:
+		return ErrNotFound
+	}
+	return nil
 }
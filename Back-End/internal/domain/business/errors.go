@@ -3,7 +3,16 @@ package business
 import "errors"
 
 var (
-	ErrInvalidName  = errors.New("business: invalid name")
-	ErrInvalidToken = errors.New("business: invalid token")
-	ErrNotFound     = errors.New("business: not found")
+	ErrInvalidName        = errors.New("business: invalid name")
+	ErrInvalidToken       = errors.New("business: invalid token")
+	ErrNotFound           = errors.New("business: not found")
+	ErrInvalidCountryCode = errors.New("business: invalid country calling code")
+	ErrInvalidCodeFormat  = errors.New("business: invalid otp code format")
+	ErrInvalidOTPTTL      = errors.New("business: invalid otp ttl")
+	ErrInvalidTokenGrace  = errors.New("business: invalid token grace period")
+	ErrInvalidStatus      = errors.New("business: invalid status")
+	ErrInvalidTransition  = errors.New("business: invalid status transition")
+	ErrSuspended          = errors.New("business: suspended")
+	// ErrTokenConflict means the token changed concurrently, e.g. two rotations raced.
+	ErrTokenConflict = errors.New("business: token changed concurrently")
 )
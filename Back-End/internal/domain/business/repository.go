@@ -1,8 +1,41 @@
 package business
 
-import "context"
+import (
+	"context"
+	"time"
+)
+
+// ListFilter selects businesses for admin listings. An empty Status lists every
+// business that is not deleted.
+type ListFilter struct {
+	Status Status
+	Limit  int
+	Offset int
+}
 
 type Repository interface {
 	Create(ctx context.Context, b Business) (Business, error)
+	// GetByID also returns deleted businesses, so admins can inspect them.
+	GetByID(ctx context.Context, id string) (Business, error)
+	List(ctx context.Context, filter ListFilter) ([]Business, error)
+	UpdateName(ctx context.Context, id string, name string) error
+	// UpdateStatus stores b.Status and b.DeletedAt.
+	UpdateStatus(ctx context.Context, b Business) error
+	// GetByToken looks the business up by HashToken(token), matching the current token or
+	// the previous one while its grace window is open. Deleted businesses are not found.
+	// The returned Token is empty.
 	GetByToken(ctx context.Context, token string) (Business, error)
+	// RotateToken stores b's token fields if the business's token hash is still
+	// currentTokenHash, otherwise it returns ErrTokenConflict.
+	RotateToken(ctx context.Context, b Business, currentTokenHash string) error
+	UpdateAllowedCountries(ctx context.Context, id string, codes []string) error
+	UpdateCodeFormat(ctx context.Context, id string, length int, charset string) error
+	UpdateOTPTTL(ctx context.Context, id string, ttl time.Duration) error
+	// UpdatePlan attaches the plan to the business; "" detaches it.
+	UpdatePlan(ctx context.Context, id string, planID string) error
+
+	// The request-signing secret has to be recoverable, so callers store it sealed.
+	// GetSigningSecret returns an empty Sealed when the business has none.
+	SetSigningSecret(ctx context.Context, id string, s SigningSecret) error
+	GetSigningSecret(ctx context.Context, id string) (SigningSecret, error)
 }
@@ -2,11 +2,17 @@ package business
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"strings"
 	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/otp"
 )
 
+// tokenPrefixLen is how much of a token is kept in clear for identification.
+const tokenPrefixLen = 8
+
 type Service struct {
 	now      func() time.Time
 	idGen    func() (string, error)
@@ -52,13 +58,129 @@ func (s *Service) NewBusiness(name string) (Business, error) {
 	}
 
 	return Business{
-		ID:        id,
-		Name:      name,
-		Token:     token,
-		CreatedAt: s.now(),
+		ID:          id,
+		Name:        name,
+		Status:      StatusActive,
+		Token:       token,
+		TokenHash:   HashToken(token),
+		TokenPrefix: TokenPrefix(token),
+		CreatedAt:   s.now(),
 	}, nil
 }
 
+func (s *Service) Rename(b Business, name string) (Business, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Business{}, ErrInvalidName
+	}
+	if b.Status == StatusDeleted {
+		return Business{}, ErrInvalidTransition
+	}
+	b.Name = name
+	return b, nil
+}
+
+func (s *Service) Suspend(b Business) (Business, error) {
+	if b.Status != StatusActive {
+		return Business{}, ErrInvalidTransition
+	}
+	b.Status = StatusSuspended
+	return b, nil
+}
+
+func (s *Service) Reactivate(b Business) (Business, error) {
+	if b.Status != StatusSuspended {
+		return Business{}, ErrInvalidTransition
+	}
+	b.Status = StatusActive
+	return b, nil
+}
+
+// Delete soft-deletes the business; it works from any status but deleted.
+func (s *Service) Delete(b Business) (Business, error) {
+	if b.Status == StatusDeleted {
+		return Business{}, ErrInvalidTransition
+	}
+	now := s.now()
+	b.Status = StatusDeleted
+	b.DeletedAt = &now
+	return b, nil
+}
+
+// RotateToken issues a new token. The current one stays valid for grace; a zero grace
+// revokes it at once. Rotating twice in a row therefore also kills a leaked token.
+func (s *Service) RotateToken(b Business, grace time.Duration) (Business, error) {
+	if grace < 0 {
+		return Business{}, ErrInvalidTokenGrace
+	}
+	token, err := s.tokenGen()
+	if err != nil {
+		return Business{}, err
+	}
+
+	b.PreviousTokenHash, b.PreviousTokenExpiresAt = "", time.Time{}
+	if grace > 0 {
+		b.PreviousTokenHash, b.PreviousTokenExpiresAt = b.TokenHash, s.now().Add(grace)
+	}
+	b.Token, b.TokenHash, b.TokenPrefix = token, HashToken(token), TokenPrefix(token)
+	return b, nil
+}
+
+// NewSigningSecret generates a secret for HMAC request signing. It is as strong as a token.
+func (s *Service) NewSigningSecret() (string, error) {
+	return s.tokenGen()
+}
+
+// SetAllowedCountries replaces the business's country allowlist. Codes may be given with
+// or without a leading "+"; duplicates are dropped.
+func (s *Service) SetAllowedCountries(b Business, codes []string) (Business, error) {
+	parsed, err := otp.NewCountryAllowlist(codes)
+	if err != nil {
+		return Business{}, ErrInvalidCountryCode
+	}
+	seen := make(map[string]bool, len(parsed))
+	allowed := make([]string, 0, len(parsed))
+	for _, c := range parsed {
+		if !seen[c] {
+			seen[c] = true
+			allowed = append(allowed, c)
+		}
+	}
+	b.AllowedCountries = allowed
+	return b, nil
+}
+
+// SetCodeFormat sets the OTP code length and charset for the business. A zero length
+// with an empty charset resets it to the deployment default.
+func (s *Service) SetCodeFormat(b Business, length int, charset string) (Business, error) {
+	charset = strings.ToLower(strings.TrimSpace(charset))
+	if length == 0 && charset == "" {
+		b.CodeLength, b.CodeCharset = 0, ""
+		return b, nil
+	}
+	format, err := otp.NewCodeFormat(length, otp.CodeCharset(charset))
+	if err != nil {
+		return Business{}, ErrInvalidCodeFormat
+	}
+	b.CodeLength, b.CodeCharset = format.Length, string(format.Charset)
+	return b, nil
+}
+
+// SetOTPTTL overrides the OTP expiry for the business; zero resets it to the default.
+// TTLs are kept in whole seconds.
+func (s *Service) SetOTPTTL(b Business, ttl time.Duration) (Business, error) {
+	if ttl == 0 {
+		b.OTPTTL = 0
+		return b, nil
+	}
+	ttl = ttl.Truncate(time.Second)
+	if _, err := otp.NewCodeTTL(ttl); err != nil {
+		return Business{}, ErrInvalidOTPTTL
+	}
+	b.OTPTTL = ttl
+	return b, nil
+}
+
 func ValidateToken(token string) error {
 	token = strings.TrimSpace(token)
 	if token == "" {
@@ -68,6 +190,20 @@ func ValidateToken(token string) error {
 	return nil
 }
 
+// HashToken is the at-rest form of an API token. Tokens are 256-bit random values, so
+// a plain SHA-256 is enough; there is nothing to brute-force.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func TokenPrefix(token string) string {
+	if len(token) <= tokenPrefixLen {
+		return ""
+	}
+	return token[:tokenPrefixLen]
+}
+
 func defaultID() (string, error) {
 	var b [16]byte
 	if _, err := rand.Read(b[:]); err != nil {
@@ -39,7 +39,172 @@ func TestService_NewBusiness_CreatesDeterministicBusiness(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if b.ID != "id1" || b.Token != "tok1" || b.Name != "Acme" || !b.CreatedAt.Equal(now) {
+	if b.ID != "id1" || b.Token != "tok1" || b.Name != "Acme" || b.Status != business.StatusActive || !b.CreatedAt.Equal(now) {
 		t.Fatalf("unexpected business: %#v", b)
 	}
+	if b.TokenHash != business.HashToken("tok1") || b.TokenHash == b.Token {
+		t.Fatalf("unexpected token hash: %q", b.TokenHash)
+	}
+}
+
+func TestService_SetAllowedCountries(t *testing.T) {
+	svc := business.NewService(business.ServiceConfig{})
+
+	b, err := svc.SetAllowedCountries(business.Business{ID: "id1"}, []string{"+98", "971", "98"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(b.AllowedCountries) != 2 || b.AllowedCountries[0] != "98" || b.AllowedCountries[1] != "971" {
+		t.Fatalf("unexpected allowed countries: %v", b.AllowedCountries)
+	}
+
+	if _, err := svc.SetAllowedCountries(b, []string{"IR"}); err != business.ErrInvalidCountryCode {
+		t.Fatalf("expected ErrInvalidCountryCode, got %v", err)
+	}
+}
+
+func TestService_SetCodeFormat(t *testing.T) {
+	svc := business.NewService(business.ServiceConfig{})
+
+	b, err := svc.SetCodeFormat(business.Business{ID: "id1"}, 8, " Alphanumeric ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.CodeLength != 8 || b.CodeCharset != "alphanumeric" {
+		t.Fatalf("unexpected code format: %d %q", b.CodeLength, b.CodeCharset)
+	}
+
+	b, err = svc.SetCodeFormat(b, 0, "")
+	if err != nil || b.CodeLength != 0 || b.CodeCharset != "" {
+		t.Fatalf("expected reset to default, got %d %q err=%v", b.CodeLength, b.CodeCharset, err)
+	}
+
+	if _, err := svc.SetCodeFormat(b, 3, "numeric"); err != business.ErrInvalidCodeFormat {
+		t.Fatalf("expected ErrInvalidCodeFormat, got %v", err)
+	}
+	if _, err := svc.SetCodeFormat(b, 6, "hex"); err != business.ErrInvalidCodeFormat {
+		t.Fatalf("expected ErrInvalidCodeFormat, got %v", err)
+	}
+}
+
+func TestService_SetOTPTTL(t *testing.T) {
+	svc := business.NewService(business.ServiceConfig{})
+
+	b, err := svc.SetOTPTTL(business.Business{ID: "id1"}, 30*time.Second)
+	if err != nil || b.OTPTTL != 30*time.Second {
+		t.Fatalf("expected 30s ttl, got %v err=%v", b.OTPTTL, err)
+	}
+
+	b, err = svc.SetOTPTTL(b, 0)
+	if err != nil || b.OTPTTL != 0 {
+		t.Fatalf("expected reset to default, got %v err=%v", b.OTPTTL, err)
+	}
+
+	if _, err := svc.SetOTPTTL(b, -time.Second); err != business.ErrInvalidOTPTTL {
+		t.Fatalf("expected ErrInvalidOTPTTL, got %v", err)
+	}
+}
+
+func TestService_RotateToken(t *testing.T) {
+	now := time.Unix(10, 0)
+	svc := business.NewService(business.ServiceConfig{
+		Now:      func() time.Time { return now },
+		TokenGen: func() (string, error) { return "tok2", nil },
+	})
+
+	current := business.Business{ID: "id1", TokenHash: business.HashToken("tok1")}
+	b, err := svc.RotateToken(current, time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.Token != "tok2" || b.TokenHash != business.HashToken("tok2") || b.PreviousTokenHash != business.HashToken("tok1") ||
+		!b.PreviousTokenExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("unexpected business: %#v", b)
+	}
+
+	current.PreviousTokenHash = business.HashToken("tok0")
+	b, err = svc.RotateToken(current, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.Token != "tok2" || b.PreviousTokenHash != "" || !b.PreviousTokenExpiresAt.IsZero() {
+		t.Fatalf("expected old token revoked, got %#v", b)
+	}
+
+	if _, err := svc.RotateToken(b, -time.Second); err != business.ErrInvalidTokenGrace {
+		t.Fatalf("expected ErrInvalidTokenGrace, got %v", err)
+	}
+}
+
+func TestTokenPrefix(t *testing.T) {
+	if got := business.TokenPrefix("0123456789abcdef"); got != "01234567" {
+		t.Fatalf("unexpected prefix: %q", got)
+	}
+	// A prefix of a short token would reveal most of it.
+	if got := business.TokenPrefix("short"); got != "" {
+		t.Fatalf("expected no prefix for short token, got %q", got)
+	}
+}
+
+func TestService_StatusTransitions(t *testing.T) {
+	now := time.Unix(10, 0)
+	svc := business.NewService(business.ServiceConfig{Now: func() time.Time { return now }})
+	b := business.Business{ID: "id1", Name: "Acme", Status: business.StatusActive}
+
+	b, err := svc.Suspend(b)
+	if err != nil || b.Status != business.StatusSuspended {
+		t.Fatalf("expected suspended, got %q err=%v", b.Status, err)
+	}
+	if _, err := svc.Suspend(b); err != business.ErrInvalidTransition {
+		t.Fatalf("expected ErrInvalidTransition suspending twice, got %v", err)
+	}
+
+	b, err = svc.Reactivate(b)
+	if err != nil || b.Status != business.StatusActive {
+		t.Fatalf("expected active, got %q err=%v", b.Status, err)
+	}
+	if _, err := svc.Reactivate(b); err != business.ErrInvalidTransition {
+		t.Fatalf("expected ErrInvalidTransition reactivating an active business, got %v", err)
+	}
+
+	b, err = svc.Delete(b)
+	if err != nil || b.Status != business.StatusDeleted || b.DeletedAt == nil || !b.DeletedAt.Equal(now) {
+		t.Fatalf("expected soft delete, got %#v err=%v", b, err)
+	}
+	for name, op := range map[string]func(business.Business) (business.Business, error){
+		"suspend":    svc.Suspend,
+		"reactivate": svc.Reactivate,
+		"delete":     svc.Delete,
+	} {
+		if _, err := op(b); err != business.ErrInvalidTransition {
+			t.Fatalf("expected deleted to be terminal for %s, got %v", name, err)
+		}
+	}
+}
+
+func TestService_Rename(t *testing.T) {
+	svc := business.NewService(business.ServiceConfig{})
+
+	b, err := svc.Rename(business.Business{Name: "Acme", Status: business.StatusActive}, "  Acme Pay ")
+	if err != nil || b.Name != "Acme Pay" {
+		t.Fatalf("expected rename, got %q err=%v", b.Name, err)
+	}
+	if _, err := svc.Rename(b, " "); err != business.ErrInvalidName {
+		t.Fatalf("expected ErrInvalidName, got %v", err)
+	}
+	if _, err := svc.Rename(business.Business{Status: business.StatusDeleted}, "x"); err != business.ErrInvalidTransition {
+		t.Fatalf("expected ErrInvalidTransition for deleted business, got %v", err)
+	}
+}
+
+func TestBusiness_CheckActive(t *testing.T) {
+	if err := (business.Business{Status: business.StatusActive}).CheckActive(); err != nil {
+		t.Fatalf("expected active business to pass, got %v", err)
+	}
+	if err := (business.Business{Status: business.StatusSuspended}).CheckActive(); err != business.ErrSuspended {
+		t.Fatalf("expected ErrSuspended, got %v", err)
+	}
+	if err := (business.Business{Status: business.StatusDeleted}).CheckActive(); err != business.ErrNotFound {
+		t.Fatalf("expected ErrNotFound for deleted business, got %v", err)
+	}
 }
@@ -0,0 +1,43 @@
+package delivery
+
+import "time"
+
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusSent      Status = "sent"
+	StatusDelivered Status = "delivered"
+	StatusFailed    Status = "failed"
+)
+
+func (s Status) Final() bool {
+	return s == StatusDelivered || s == StatusFailed
+}
+
+// Delivery tracks one OTP send request from enqueue to the provider's delivery report.
+type Delivery struct {
+	ID          string
+	BusinessID  string
+	MaskedPhone string
+	Status      Status
+
+	// Provider and ProviderMessageID are set once a gateway accepts the message;
+	// the message ID is what delivery-report webhooks refer to.
+	Provider          string
+	ProviderMessageID string
+	FailureReason     string
+
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	SentAt      *time.Time
+	DeliveredAt *time.Time
+}
+
+// MaskPhone keeps only enough digits to recognise a number: 0912***6789.
+func MaskPhone(phone string) string {
+	if len(phone) <= 7 {
+		return "***"
+	}
+	return phone[:len(phone)-7] + "***" + phone[len(phone)-4:]
+}
@@ -0,0 +1,14 @@
+package delivery
+
+import "errors"
+
+var (
+	ErrInvalidBusiness = errors.New("delivery: invalid business id")
+	ErrInvalidStatus   = errors.New("delivery: invalid status transition")
+	ErrInvalidProvider = errors.New("delivery: invalid provider")
+	ErrNotFound        = errors.New("delivery: not found")
+	// ErrNotSent means a report arrived before the send it refers to was recorded.
+	ErrNotSent = errors.New("delivery: not sent yet")
+	// ErrStatusConflict means the status changed concurrently, e.g. two reports raced.
+	ErrStatusConflict = errors.New("delivery: status changed concurrently")
+)
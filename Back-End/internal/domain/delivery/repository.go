@@ -0,0 +1,16 @@
+package delivery
+
+import "context"
+
+type Repository interface {
+	Create(ctx context.Context, d Delivery) error
+	// Update stores d if the delivery's status is still from, otherwise it returns
+	// ErrStatusConflict.
+	Update(ctx context.Context, d Delivery, from Status) error
+
+	// Get only returns deliveries owned by businessID, so one business can never read another's records.
+	Get(ctx context.Context, businessID, id string) (Delivery, error)
+	// GetByID is for internal status updates only; never expose it to business-facing callers.
+	GetByID(ctx context.Context, id string) (Delivery, error)
+	GetByProviderMessageID(ctx context.Context, provider, messageID string) (Delivery, error)
+}
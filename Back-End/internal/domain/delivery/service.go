@@ -0,0 +1,110 @@
+package delivery
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+type Service struct {
+	now   func() time.Time
+	idGen func() (string, error)
+}
+
+type ServiceConfig struct {
+	Now   func() time.Time
+	IDGen func() (string, error)
+}
+
+func NewService(cfg ServiceConfig) *Service {
+	now := cfg.Now
+	if now == nil {
+		now = time.Now
+	}
+	idGen := cfg.IDGen
+	if idGen == nil {
+		idGen = defaultID
+	}
+	return &Service{now: now, idGen: idGen}
+}
+
+// NewDelivery starts tracking a send. requestID lets the caller reuse an ID it has
+// already handed out (e.g. the HTTP request ID); an empty one is generated.
+func (s *Service) NewDelivery(requestID, businessID, phone string) (Delivery, error) {
+	if strings.TrimSpace(businessID) == "" {
+		return Delivery{}, ErrInvalidBusiness
+	}
+	id := strings.TrimSpace(requestID)
+	if id == "" {
+		var err error
+		if id, err = s.idGen(); err != nil {
+			return Delivery{}, err
+		}
+	}
+	now := s.now()
+	return Delivery{
+		ID:          id,
+		BusinessID:  businessID,
+		MaskedPhone: MaskPhone(phone),
+		Status:      StatusQueued,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, nil
+}
+
+func (s *Service) MarkSent(d Delivery, provider, messageID string) (Delivery, error) {
+	if strings.TrimSpace(provider) == "" {
+		return Delivery{}, ErrInvalidProvider
+	}
+	if d.Status != StatusQueued {
+		return Delivery{}, ErrInvalidStatus
+	}
+	now := s.now()
+	d.Status = StatusSent
+	d.Provider = provider
+	d.ProviderMessageID = messageID
+	d.SentAt = &now
+	d.UpdatedAt = now
+	return d, nil
+}
+
+func (s *Service) MarkFailed(d Delivery, reason string) (Delivery, error) {
+	if d.Status.Final() {
+		return Delivery{}, ErrInvalidStatus
+	}
+	d.Status = StatusFailed
+	d.FailureReason = reason
+	d.UpdatedAt = s.now()
+	return d, nil
+}
+
+// ApplyReport records a provider delivery report. Only sent messages can be
+// reported on: a report that beats the send is refused with ErrNotSent, and reports
+// for a message already in a final state are rejected so late or duplicated
+// webhooks can't flip the outcome.
+func (s *Service) ApplyReport(d Delivery, delivered bool, reason string) (Delivery, error) {
+	switch d.Status {
+	case StatusSent:
+	case StatusQueued:
+		return Delivery{}, ErrNotSent
+	default:
+		return Delivery{}, ErrInvalidStatus
+	}
+	if !delivered {
+		return s.MarkFailed(d, reason)
+	}
+	now := s.now()
+	d.Status = StatusDelivered
+	d.DeliveredAt = &now
+	d.UpdatedAt = now
+	return d, nil
+}
+
+func defaultID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
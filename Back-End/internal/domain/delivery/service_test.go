@@ -0,0 +1,86 @@
+package delivery_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/delivery"
+)
+
+func newTestService(now time.Time) *delivery.Service {
+	return delivery.NewService(delivery.ServiceConfig{
+		Now: func() time.Time { return now },
+		IDGen: func() (string, error) {
+			return "req1", nil
+		},
+	})
+}
+
+func TestService_NewDelivery(t *testing.T) {
+	now := time.Unix(100, 0)
+	svc := newTestService(now)
+
+	if _, err := svc.NewDelivery("", " ", "09123456789"); err != delivery.ErrInvalidBusiness {
+		t.Fatalf("expected ErrInvalidBusiness, got %v", err)
+	}
+
+	d, err := svc.NewDelivery("", "b1", "09123456789")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.ID != "req1" || d.BusinessID != "b1" || d.Status != delivery.StatusQueued || !d.CreatedAt.Equal(now) {
+		t.Fatalf("unexpected delivery: %#v", d)
+	}
+	if d.MaskedPhone != "0912***6789" {
+		t.Fatalf("expected masked phone, got %q", d.MaskedPhone)
+	}
+
+	d, _ = svc.NewDelivery("given", "b1", "09123456789")
+	if d.ID != "given" {
+		t.Fatalf("expected caller supplied id, got %q", d.ID)
+	}
+}
+
+func TestService_StatusTransitions(t *testing.T) {
+	svc := newTestService(time.Unix(100, 0))
+	d, _ := svc.NewDelivery("", "b1", "09123456789")
+
+	if _, err := svc.ApplyReport(d, true, ""); err != delivery.ErrNotSent {
+		t.Fatalf("expected report on queued delivery to wait for the send, got %v", err)
+	}
+
+	sent, err := svc.MarkSent(d, "kavenegar", "m1")
+	if err != nil || sent.Status != delivery.StatusSent || sent.Provider != "kavenegar" || sent.SentAt == nil {
+		t.Fatalf("unexpected sent delivery %#v err=%v", sent, err)
+	}
+
+	delivered, err := svc.ApplyReport(sent, true, "")
+	if err != nil || delivered.Status != delivery.StatusDelivered || delivered.DeliveredAt == nil {
+		t.Fatalf("unexpected delivered delivery %#v err=%v", delivered, err)
+	}
+
+	if _, err := svc.ApplyReport(delivered, false, "late report"); err != delivery.ErrInvalidStatus {
+		t.Fatalf("expected final status to be kept, got %v", err)
+	}
+	if _, err := svc.MarkFailed(delivered, "late"); err != delivery.ErrInvalidStatus {
+		t.Fatalf("expected final status to be kept, got %v", err)
+	}
+
+	failed, err := svc.ApplyReport(sent, false, "unreachable")
+	if err != nil || failed.Status != delivery.StatusFailed || failed.FailureReason != "unreachable" {
+		t.Fatalf("unexpected failed delivery %#v err=%v", failed, err)
+	}
+}
+
+func TestMaskPhone(t *testing.T) {
+	tests := map[string]string{
+		"09123456789":   "0912***6789",
+		"+989123456789": "+98912***6789",
+		"123":           "***",
+	}
+	for in, want := range tests {
+		if got := delivery.MaskPhone(in); got != want {
+			t.Errorf("MaskPhone(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package otp
 
 import (
 	"regexp"
+	"strings"
 	"time"
 )
 
@@ -9,11 +10,20 @@ type IranPhoneNumber string
 
 var iranPhoneRegex = regexp.MustCompile(`^(?:\+98|0)?9\d{9}$`)
 
+// NewIranPhoneNumber accepts 09123456789, 9123456789 and +989123456789 and always
+// returns the canonical E.164 form (+989123456789), so every input form of the same
+// number ends up under the same OTP key.
 func NewIranPhoneNumber(value string) (IranPhoneNumber, error) {
 	if !iranPhoneRegex.MatchString(value) {
 		return "", ErrInvalidPhone
 	}
-	return IranPhoneNumber(value), nil
+	national := strings.TrimPrefix(value, "+98")
+	national = strings.TrimPrefix(national, "0")
+	return IranPhoneNumber("+98" + national), nil
+}
+
+func (p IranPhoneNumber) PhoneNumber() PhoneNumber {
+	return PhoneNumber(p)
 }
 
 type CodeTTL time.Duration
@@ -25,13 +35,102 @@ func NewCodeTTL(ttl time.Duration) (CodeTTL, error) {
 	return CodeTTL(ttl), nil
 }
 
+type MaxAttempts int
+
+func NewMaxAttempts(n int) (MaxAttempts, error) {
+	if n <= 0 {
+		return MaxAttempts(n), ErrInvalidMaxAttempts
+	}
+	return MaxAttempts(n), nil
+}
+
+// CodeCharset is the alphabet OTP codes are drawn from.
+type CodeCharset string
+
+const (
+	CodeCharsetNumeric CodeCharset = "numeric"
+	// CodeCharsetAlphanumeric uses digits and upper-case letters; codes are case-sensitive.
+	CodeCharsetAlphanumeric CodeCharset = "alphanumeric"
+)
+
+const (
+	MinCodeLength = 4
+	MaxCodeLength = 10
+)
+
+var codeAlphabets = map[CodeCharset]string{
+	CodeCharsetNumeric:      "0123456789",
+	CodeCharsetAlphanumeric: "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+}
+
+// CodeFormat is the length and charset of generated codes. The zero value means
+// "use the service default".
+type CodeFormat struct {
+	Length  int
+	Charset CodeCharset
+}
+
+func NewCodeFormat(length int, charset CodeCharset) (CodeFormat, error) {
+	if length < MinCodeLength || length > MaxCodeLength {
+		return CodeFormat{}, ErrInvalidCodeFormat
+	}
+	if _, ok := codeAlphabets[charset]; !ok {
+		return CodeFormat{}, ErrInvalidCodeFormat
+	}
+	return CodeFormat{Length: length, Charset: charset}, nil
+}
+
+func (f CodeFormat) IsZero() bool {
+	return f == CodeFormat{}
+}
+
+// Matches reports whether code has exactly the format's length and only uses its alphabet.
+func (f CodeFormat) Matches(code string) bool {
+	alphabet, ok := codeAlphabets[f.Charset]
+	if !ok || len(code) != f.Length {
+		return false
+	}
+	for _, c := range code {
+		if !strings.ContainsRune(alphabet, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// SendLimits throttles how often a business can send codes to the same phone number.
+// A zero value for any field disables that limit.
+type SendLimits struct {
+	Cooldown time.Duration
+	PerHour  int
+	PerDay   int
+}
+
+func NewSendLimits(cooldown time.Duration, perHour, perDay int) (SendLimits, error) {
+	if cooldown < 0 || perHour < 0 || perDay < 0 {
+		return SendLimits{}, ErrInvalidSendLimits
+	}
+	return SendLimits{Cooldown: cooldown, PerHour: perHour, PerDay: perDay}, nil
+}
+
 type OTP struct {
 	BusinessID  string
-	PhoneNumber IranPhoneNumber
-	Code        string
-	ExpiresAt   time.Time
+	PhoneNumber PhoneNumber
+	// Code is the plaintext code. It only lives in memory long enough to be delivered
+	// and is never persisted; repositories store CodeHash instead.
+	Code      string
+	CodeHash  string
+	ExpiresAt time.Time
+
+	// Attempts counts failed verifications; once it reaches MaxAttempts the OTP is locked.
+	Attempts    int
+	MaxAttempts MaxAttempts
 }
 
 func (o OTP) Expired(now time.Time) bool {
 	return !o.ExpiresAt.After(now)
 }
+
+func (o OTP) Locked() bool {
+	return o.MaxAttempts > 0 && o.Attempts >= int(o.MaxAttempts)
+}
@@ -1,11 +1,49 @@
 package otp
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
-	ErrInvalidPhone    = errors.New("otp: invalid phone number")
-	ErrInvalidTTL      = errors.New("otp: invalid ttl")
-	ErrInvalidCode     = errors.New("otp: invalid code")
-	ErrInvalidBusiness = errors.New("otp: invalid business id")
-	ErrNotFound        = errors.New("otp: not found")
+	ErrInvalidPhone       = errors.New("otp: invalid phone number")
+	ErrInvalidTTL         = errors.New("otp: invalid ttl")
+	ErrInvalidCode        = errors.New("otp: invalid code")
+	ErrInvalidBusiness    = errors.New("otp: invalid business id")
+	ErrNotFound           = errors.New("otp: not found")
+	ErrInvalidMaxAttempts = errors.New("otp: invalid max attempts")
+	ErrTooManyAttempts    = errors.New("otp: too many failed attempts")
+	ErrInvalidSendLimits  = errors.New("otp: invalid send limits")
+	ErrRateLimited        = errors.New("otp: rate limited")
+	ErrQuotaExceeded      = errors.New("otp: monthly quota exceeded")
+	ErrInvalidCodeSecret  = errors.New("otp: invalid code secret")
+	ErrInvalidCountryCode = errors.New("otp: invalid country code")
+	ErrCountryNotAllowed  = errors.New("otp: phone country not allowed")
+	ErrInvalidCodeFormat  = errors.New("otp: invalid code format")
+	ErrBusinessSuspended  = errors.New("otp: business suspended")
+)
+
+const (
+	RateLimitReasonCooldown  = "cooldown"
+	RateLimitReasonHourlyCap = "hourly_limit"
+	RateLimitReasonDailyCap  = "daily_limit"
+	// RateLimitReasonMonthlyQuota is the business's plan quota, not a per-phone limit.
+	RateLimitReasonMonthlyQuota = "monthly_quota"
 )
+
+// RateLimitError is returned when sending to a phone number is throttled, or when the
+// business has used up its monthly quota (which also matches ErrQuotaExceeded).
+// RetryAfter is how long the caller must wait before the next send is accepted.
+type RateLimitError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("otp: rate limited (%s), retry after %s", e.Reason, e.RetryAfter)
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited || (target == ErrQuotaExceeded && e.Reason == RateLimitReasonMonthlyQuota)
+}
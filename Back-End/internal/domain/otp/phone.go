@@ -0,0 +1,118 @@
+package otp
+
+import (
+	"regexp"
+	"strings"
+)
+
+// PhoneNumber is a mobile number in canonical E.164 form, e.g. +989123456789.
+// Repositories key OTPs by it, so every accepted input form must map to one value.
+type PhoneNumber string
+
+var e164Regex = regexp.MustCompile(`^\+[1-9]\d{7,14}$`)
+
+// Region is a country we know the mobile numbering plan of.
+type Region struct {
+	Name        string
+	CallingCode string
+	mobile      *regexp.Regexp
+}
+
+// regions holds per-country mobile rules. Numbers from other countries are accepted
+// on E.164 shape alone and left to the business's country allowlist.
+var regions = []Region{
+	{Name: "IR", CallingCode: "98", mobile: regexp.MustCompile(`^9\d{9}$`)},
+	{Name: "AE", CallingCode: "971", mobile: regexp.MustCompile(`^5\d{8}$`)},
+	{Name: "IQ", CallingCode: "964", mobile: regexp.MustCompile(`^7\d{9}$`)},
+	{Name: "TR", CallingCode: "90", mobile: regexp.MustCompile(`^5\d{9}$`)},
+}
+
+// NewPhoneNumber parses an international number (+<code>… or 00<code>…). Iranian
+// local forms (09…, 9…) are still accepted since Iran is our home region.
+func NewPhoneNumber(value string) (PhoneNumber, error) {
+	if ir, err := NewIranPhoneNumber(value); err == nil {
+		return ir.PhoneNumber(), nil
+	}
+
+	v := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(strings.TrimSpace(value))
+	if strings.HasPrefix(v, "00") {
+		v = "+" + v[2:]
+	}
+	if !e164Regex.MatchString(v) {
+		return "", ErrInvalidPhone
+	}
+
+	p := PhoneNumber(v)
+	if r, ok := p.Region(); ok && !r.mobile.MatchString(p.national(r)) {
+		return "", ErrInvalidPhone
+	}
+	return p, nil
+}
+
+// Region returns the known region the number belongs to, if any.
+func (p PhoneNumber) Region() (Region, bool) {
+	for _, r := range regions {
+		if p.HasCallingCode(r.CallingCode) {
+			return r, true
+		}
+	}
+	return Region{}, false
+}
+
+// HasCallingCode reports whether the number is in the given country calling code.
+func (p PhoneNumber) HasCallingCode(code string) bool {
+	return code != "" && p.CallingCode() == code
+}
+
+// CallingCode returns the number's country calling code, or "" when it doesn't start
+// with an assigned one.
+func (p PhoneNumber) CallingCode() string {
+	digits := strings.TrimPrefix(string(p), "+")
+	for n := 1; n <= 3 && n <= len(digits); n++ {
+		if callingCodes[digits[:n]] {
+			return digits[:n]
+		}
+	}
+	return ""
+}
+
+// National returns the number as dialled inside its own country (09123456789 for
+// Iran). Numbers from unknown regions are returned unchanged.
+func (p PhoneNumber) National() string {
+	r, ok := p.Region()
+	if !ok {
+		return string(p)
+	}
+	return "0" + p.national(r)
+}
+
+func (p PhoneNumber) national(r Region) string {
+	return strings.TrimPrefix(string(p), "+"+r.CallingCode)
+}
+
+// CountryAllowlist restricts which country calling codes a business may send to.
+type CountryAllowlist []string
+
+// NewCountryAllowlist accepts only assigned calling codes, so a typo or a partial
+// code such as "9" is refused rather than silently matching nothing or too much.
+func NewCountryAllowlist(codes []string) (CountryAllowlist, error) {
+	out := make(CountryAllowlist, 0, len(codes))
+	for _, c := range codes {
+		c = strings.TrimPrefix(strings.TrimSpace(c), "+")
+		if !callingCodes[c] {
+			return nil, ErrInvalidCountryCode
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (a CountryAllowlist) Allows(p PhoneNumber) bool {
+	code := p.CallingCode()
+	for _, c := range a {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
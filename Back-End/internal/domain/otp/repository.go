@@ -2,12 +2,26 @@ package otp
 
 import "context"
 
+// CodeCheck decides whether the code presented for a pending OTP is correct; it is
+// normally Service.Verify bound to the presented code. Consume passes it the zero OTP
+// when nothing is pending.
+type CodeCheck func(stored OTP) (bool, error)
+
 type Repository interface {
+	// Save stores the OTP, replacing any pending one for the same phone number.
+	// It returns a *RateLimitError, without storing anything, when the business is
+	// still in the resend cooldown or over its hourly/daily cap for that phone.
 	Save(ctx context.Context, otp OTP) error
-	Get(ctx context.Context, businessID string, phone IranPhoneNumber) (OTP, error)
-	Delete(ctx context.Context, businessID string, phone IranPhoneNumber) error
+	Get(ctx context.Context, businessID string, phone PhoneNumber) (OTP, error)
+	Delete(ctx context.Context, businessID string, phone PhoneNumber) error
+	// Release undoes the Save of an OTP that never reached the gateway: it deletes the
+	// OTP and gives back the cooldown and the hourly/daily send it was counted as. It
+	// does nothing once the OTP has been replaced or consumed.
+	Release(ctx context.Context, otp OTP) error
 
-	// Consume atomically verifies the provided code and deletes the OTP if it matches.
-	// This is required to guarantee single-use semantics under concurrent verification attempts.
-	Consume(ctx context.Context, businessID string, phone IranPhoneNumber, code string) (bool, error)
+	// Consume runs check against the pending OTP and deletes the OTP if it matches, atomically,
+	// so a code is single-use even under concurrent verification attempts. Errors from check are
+	// returned as-is and change nothing. Every mismatch counts as a failed attempt; once MaxAttempts
+	// is reached the OTP is locked and Consume returns ErrTooManyAttempts until it expires or is replaced.
+	Consume(ctx context.Context, businessID string, phone PhoneNumber, check CodeCheck) (bool, error)
 }
@@ -2,73 +2,134 @@ package otp
 
 import (
 	"crypto/rand"
-	"fmt"
 	"math/big"
-	"regexp"
 	"strings"
 	"time"
 )
 
-var (
-	codeRe = regexp.MustCompile(`^\d{6}$`)
-)
+const DefaultMaxAttempts MaxAttempts = 5
+
+// DefaultCodeFormat is the original 6-digit numeric code.
+var DefaultCodeFormat = CodeFormat{Length: 6, Charset: CodeCharsetNumeric}
+
+// DefaultCountries keeps the original Iran-only behaviour when nothing is configured.
+var DefaultCountries = CountryAllowlist{"98"}
 
 type Service struct {
-	now     func() time.Time
-	ttl     CodeTTL
-	codeGen func() (string, error)
+	now         func() time.Time
+	ttl         CodeTTL
+	maxAttempts MaxAttempts
+	hasher      CodeHasher
+	countries   CountryAllowlist
+	codeFormat  CodeFormat
+	codeGen     func(CodeFormat) (string, error)
 }
 
 type ServiceConfig struct {
-	Now     func() time.Time
-	TTL     CodeTTL
+	Now         func() time.Time
+	TTL         CodeTTL
+	MaxAttempts MaxAttempts
+	Hasher      CodeHasher
+	// Countries is the default allowlist for businesses without one of their own.
+	Countries CountryAllowlist
+	// CodeFormat is the default for businesses without one of their own.
+	CodeFormat CodeFormat
+	// CodeGen overrides code generation; the generated code must still match the format.
 	CodeGen func() (string, error)
 }
 
+// Policy carries per-business overrides; zero fields fall back to the service defaults.
+type Policy struct {
+	Countries  CountryAllowlist
+	CodeFormat CodeFormat
+	TTL        CodeTTL
+	// Suspended blocks sending and verifying. Pending OTPs are kept, so they work
+	// again if the business is reactivated before they expire.
+	Suspended bool
+}
+
 func NewService(cfg ServiceConfig) *Service {
 	now := cfg.Now
 	if now == nil {
 		now = time.Now
 	}
-	codeGen := cfg.CodeGen
-	if codeGen == nil {
-		codeGen = defaultCode
+	codeGen := defaultCode
+	if cfg.CodeGen != nil {
+		codeGen = func(CodeFormat) (string, error) { return cfg.CodeGen() }
+	}
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	countries := cfg.Countries
+	if len(countries) == 0 {
+		countries = DefaultCountries
+	}
+	codeFormat := cfg.CodeFormat
+	if codeFormat.IsZero() {
+		codeFormat = DefaultCodeFormat
 	}
 	return &Service{
-		now:     now,
-		ttl:     cfg.TTL,
-		codeGen: codeGen,
+		now:         now,
+		ttl:         cfg.TTL,
+		maxAttempts: maxAttempts,
+		hasher:      cfg.Hasher,
+		countries:   countries,
+		codeFormat:  codeFormat,
+		codeGen:     codeGen,
 	}
 }
 
-func (s *Service) NewOTP(businessID string, phone IranPhoneNumber) (OTP, error) {
+func (s *Service) NewOTP(businessID string, phone PhoneNumber, policy Policy) (OTP, error) {
 	if strings.TrimSpace(businessID) == "" {
 		return OTP{}, ErrInvalidBusiness
 	}
-	code, err := s.codeGen()
+	if policy.Suspended {
+		return OTP{}, ErrBusinessSuspended
+	}
+	countries := policy.Countries
+	if len(countries) == 0 {
+		countries = s.countries
+	}
+	if !countries.Allows(phone) {
+		return OTP{}, ErrCountryNotAllowed
+	}
+	format := s.CodeFormat(policy)
+	code, err := s.codeGen(format)
 	if err != nil {
 		return OTP{}, err
 	}
-	if err := ValidateCode(code); err != nil {
+	if err := ValidateCode(code, format); err != nil {
 		return OTP{}, err
 	}
 
+	ttl := policy.TTL
+	if ttl <= 0 {
+		ttl = s.ttl
+	}
+
 	now := s.now()
 	return OTP{
 		BusinessID:  businessID,
 		PhoneNumber: phone,
 		Code:        code,
-		ExpiresAt:   now.Add(time.Duration(s.ttl)),
+		CodeHash:    s.hasher.Hash(businessID, phone, code),
+		ExpiresAt:   now.Add(time.Duration(ttl)),
+		MaxAttempts: s.maxAttempts,
 	}, nil
 }
 
-func (s *Service) Verify(stored OTP, businessID string, phone IranPhoneNumber, code string) (bool, error) {
-	if err := ValidateCode(code); err != nil {
+func (s *Service) Verify(stored OTP, businessID string, phone PhoneNumber, code string, policy Policy) (bool, error) {
+	code = strings.TrimSpace(code)
+	if err := ValidateCode(code, s.CodeFormat(policy)); err != nil {
 		return false, err
 	}
 	if strings.TrimSpace(businessID) == "" {
 		return false, ErrInvalidBusiness
 	}
+	if policy.Suspended {
+		return false, ErrBusinessSuspended
+	}
 
 	if stored.BusinessID != businessID || stored.PhoneNumber != phone {
 		return false, nil
@@ -76,22 +137,38 @@ func (s *Service) Verify(stored OTP, businessID string, phone IranPhoneNumber, c
 	if stored.Expired(s.now()) {
 		return false, nil
 	}
-	return stored.Code == code, nil
+	if stored.Locked() {
+		return false, ErrTooManyAttempts
+	}
+	return s.hasher.Equal(stored.CodeHash, businessID, phone, code), nil
 }
 
-func ValidateCode(code string) error {
+// CodeFormat returns the code format that applies under the given policy.
+func (s *Service) CodeFormat(policy Policy) CodeFormat {
+	if policy.CodeFormat.IsZero() {
+		return s.codeFormat
+	}
+	return policy.CodeFormat
+}
+
+func ValidateCode(code string, format CodeFormat) error {
 	code = strings.TrimSpace(code)
-	if !codeRe.MatchString(code) {
+	if !format.Matches(code) {
 		return ErrInvalidCode
 	}
 	return nil
 }
 
-func defaultCode() (string, error) {
-	// 6-digit numeric OTP.
-	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
-	if err != nil {
-		return "", err
+func defaultCode(format CodeFormat) (string, error) {
+	alphabet := codeAlphabets[format.Charset]
+	max := big.NewInt(int64(len(alphabet)))
+	b := make([]byte, format.Length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = alphabet[n.Int64()]
 	}
-	return fmt.Sprintf("%06d", n.Int64()), nil
+	return string(b), nil
 }
@@ -34,7 +34,7 @@ func TestService_NewOTP_ValidatesPhoneAndBusiness(t *testing.T) {
 		TTL: ttl,
 	})
 	// TODO: remove check business and replace it with businessID type
-	if _, err := svc.NewOTP("", "+15551234567"); err != otp.ErrInvalidBusiness {
+	if _, err := svc.NewOTP("", "+15551234567", otp.Policy{}); err != otp.ErrInvalidBusiness {
 		t.Fatalf("expected ErrInvalidBusiness, got %v", err)
 	}
 }
@@ -53,54 +53,281 @@ func TestService_NewOTP_GeneratesDeterministicOTP(t *testing.T) {
 		},
 	})
 
-	o, err := svc.NewOTP("b1", "+15551234567")
+	o, err := svc.NewOTP("b1", "+15551234567", otp.Policy{Countries: otp.CountryAllowlist{"1"}})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if o.Code != "123456" {
 		t.Fatalf("expected code 123456, got %q", o.Code)
 	}
+	if o.CodeHash == "" || o.CodeHash == o.Code {
+		t.Fatalf("expected code hash to be set, got %q", o.CodeHash)
+	}
 	if !o.ExpiresAt.Equal(now.Add(2 * time.Minute)) {
 		t.Fatalf("unexpected ExpiresAt: %v", o.ExpiresAt)
 	}
 }
 
+func newTestHasher(t *testing.T) otp.CodeHasher {
+	t.Helper()
+	h, err := otp.NewCodeHasher([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("expected No Error for create hasher, got %v", err)
+	}
+	return h
+}
+
+func Test_NewCodeHasher(t *testing.T) {
+	if _, err := otp.NewCodeHasher([]byte("short")); err != otp.ErrInvalidCodeSecret {
+		t.Fatalf("expected ErrInvalidCodeSecret, got %v", err)
+	}
+
+	h := newTestHasher(t)
+	hash := h.Hash("b1", "9123456789", "123456")
+	if hash == "123456" || !h.Equal(hash, "b1", "9123456789", "123456") {
+		t.Fatalf("expected hash to round-trip, got %q", hash)
+	}
+	if h.Equal(hash, "b2", "9123456789", "123456") || h.Equal(hash, "b1", "9120000000", "123456") {
+		t.Fatalf("expected hash to be bound to business and phone")
+	}
+}
+
 func TestService_Verify(t *testing.T) {
 	ttl, err := otp.NewCodeTTL(2 * time.Minute)
 	if err != nil {
 		t.Fatalf("expected No Error for create valid ttl, got %v", err)
 	}
 	now := time.Unix(100, 0)
+	hasher := newTestHasher(t)
 	svc := otp.NewService(otp.ServiceConfig{
-		Now: func() time.Time { return now },
-		TTL: ttl,
+		Now:    func() time.Time { return now },
+		TTL:    ttl,
+		Hasher: hasher,
 	})
 
 	stored := otp.OTP{
 		BusinessID:  "b1",
 		PhoneNumber: "+15551234567",
-		Code:        "123456",
+		CodeHash:    hasher.Hash("b1", "+15551234567", "123456"),
 		ExpiresAt:   now.Add(1 * time.Minute),
 	}
 
-	ok, err := svc.Verify(stored, "b1", "+15551234567", "123456")
+	ok, err := svc.Verify(stored, "b1", "+15551234567", "123456", otp.Policy{})
 	if err != nil || !ok {
 		t.Fatalf("expected ok, got ok=%v err=%v", ok, err)
 	}
 
-	ok, err = svc.Verify(stored, "b1", "+15551234567", "000000")
+	ok, err = svc.Verify(stored, "b1", "+15551234567", "000000", otp.Policy{})
 	if err != nil || ok {
 		t.Fatalf("expected mismatch false,nil got ok=%v err=%v", ok, err)
 	}
 
 	expired := stored
 	expired.ExpiresAt = now.Add(-time.Second)
-	ok, err = svc.Verify(expired, "b1", "+15551234567", "123456")
+	ok, err = svc.Verify(expired, "b1", "+15551234567", "123456", otp.Policy{})
 	if err != nil || ok {
 		t.Fatalf("expected expired false,nil got ok=%v err=%v", ok, err)
 	}
 }
 
+func Test_NewMaxAttempts(t *testing.T) {
+	if _, err := otp.NewMaxAttempts(0); err != otp.ErrInvalidMaxAttempts {
+		t.Fatalf("expected ErrInvalidMaxAttempts, got %v", err)
+	}
+	n, err := otp.NewMaxAttempts(3)
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3,nil got %v,%v", n, err)
+	}
+}
+
+func Test_NewSendLimits(t *testing.T) {
+	if _, err := otp.NewSendLimits(-time.Second, 5, 10); err != otp.ErrInvalidSendLimits {
+		t.Fatalf("expected ErrInvalidSendLimits, got %v", err)
+	}
+	if _, err := otp.NewSendLimits(time.Minute, -1, 10); err != otp.ErrInvalidSendLimits {
+		t.Fatalf("expected ErrInvalidSendLimits, got %v", err)
+	}
+	l, err := otp.NewSendLimits(time.Minute, 5, 10)
+	if err != nil || l.Cooldown != time.Minute || l.PerHour != 5 || l.PerDay != 10 {
+		t.Fatalf("unexpected limits %#v err=%v", l, err)
+	}
+}
+
+func TestService_NewOTP_SetsMaxAttempts(t *testing.T) {
+	ttl, _ := otp.NewCodeTTL(time.Minute)
+	svc := otp.NewService(otp.ServiceConfig{TTL: ttl})
+	o, err := svc.NewOTP("b1", "+989123456789", otp.Policy{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if o.MaxAttempts != otp.DefaultMaxAttempts {
+		t.Fatalf("expected default max attempts, got %d", o.MaxAttempts)
+	}
+
+	svc = otp.NewService(otp.ServiceConfig{TTL: ttl, MaxAttempts: 3})
+	o, _ = svc.NewOTP("b1", "+989123456789", otp.Policy{})
+	if o.MaxAttempts != 3 {
+		t.Fatalf("expected max attempts 3, got %d", o.MaxAttempts)
+	}
+}
+
+func TestService_Verify_LockedAfterMaxAttempts(t *testing.T) {
+	ttl, _ := otp.NewCodeTTL(2 * time.Minute)
+	now := time.Unix(100, 0)
+	svc := otp.NewService(otp.ServiceConfig{
+		Now: func() time.Time { return now },
+		TTL: ttl,
+	})
+
+	stored := otp.OTP{
+		BusinessID:  "b1",
+		PhoneNumber: "+15551234567",
+		Code:        "123456",
+		ExpiresAt:   now.Add(1 * time.Minute),
+		Attempts:    3,
+		MaxAttempts: 3,
+	}
+
+	ok, err := svc.Verify(stored, "b1", "+15551234567", "123456", otp.Policy{})
+	if err != otp.ErrTooManyAttempts || ok {
+		t.Fatalf("expected ErrTooManyAttempts, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestService_NewOTP_EnforcesCountryAllowlist(t *testing.T) {
+	ttl, _ := otp.NewCodeTTL(time.Minute)
+	svc := otp.NewService(otp.ServiceConfig{TTL: ttl})
+
+	if _, err := svc.NewOTP("b1", "+15551234567", otp.Policy{}); err != otp.ErrCountryNotAllowed {
+		t.Fatalf("expected default allowlist to reject US number, got %v", err)
+	}
+	if _, err := svc.NewOTP("b1", "+989123456789", otp.Policy{}); err != nil {
+		t.Fatalf("expected default allowlist to accept Iranian number, got %v", err)
+	}
+
+	policy := otp.Policy{Countries: otp.CountryAllowlist{"971"}}
+	if _, err := svc.NewOTP("b1", "+971501234567", policy); err != nil {
+		t.Fatalf("expected business allowlist to accept UAE number, got %v", err)
+	}
+	if _, err := svc.NewOTP("b1", "+989123456789", policy); err != otp.ErrCountryNotAllowed {
+		t.Fatalf("expected business allowlist to override default, got %v", err)
+	}
+}
+
+func Test_NewCountryAllowlist(t *testing.T) {
+	a, err := otp.NewCountryAllowlist([]string{"98", "+971", " 1 "})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(a) != 3 || a[1] != "971" || a[2] != "1" {
+		t.Fatalf("unexpected allowlist %v", a)
+	}
+	for _, bad := range []string{"", "0", "9999", "ir", "9", "97", "42", "384"} {
+		if _, err := otp.NewCountryAllowlist([]string{bad}); err != otp.ErrInvalidCountryCode {
+			t.Fatalf("expected ErrInvalidCountryCode for %q, got %v", bad, err)
+		}
+	}
+}
+
+func Test_CountryAllowlist_MatchesCallingCode(t *testing.T) {
+	tests := []struct {
+		phone otp.PhoneNumber
+		code  string
+	}{
+		{"+15551234567", "1"},
+		{"+79123456789", "7"},
+		{"+971501234567", "971"},
+		{"+989123456789", "98"},
+		{"+38412345678", ""},
+	}
+	for _, tt := range tests {
+		if got := tt.phone.CallingCode(); got != tt.code {
+			t.Errorf("%s: expected calling code %q, got %q", tt.phone, tt.code, got)
+		}
+	}
+
+	// A partial code no longer matches every number that starts with it.
+	if (otp.CountryAllowlist{"97"}).Allows("+971501234567") {
+		t.Fatalf("expected \"97\" not to allow a UAE number")
+	}
+	if !(otp.CountryAllowlist{"971"}).Allows("+971501234567") {
+		t.Fatalf("expected \"971\" to allow a UAE number")
+	}
+}
+
+func Test_NewPhoneNumber(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected otp.PhoneNumber
+		national string
+		wantErr  bool
+	}{
+		{input: "+989123456789", expected: "+989123456789", national: "09123456789"},
+		{input: "09123456789", expected: "+989123456789", national: "09123456789"},
+		{input: "9123456789", expected: "+989123456789", national: "09123456789"},
+		{input: "00989123456789", expected: "+989123456789", national: "09123456789"},
+		{input: "+971 50 123 4567", expected: "+971501234567", national: "0501234567"},
+		{input: "+905321234567", expected: "+905321234567", national: "05321234567"},
+		{input: "+4915112345678", expected: "+4915112345678", national: "+4915112345678"},
+		{input: "+15551234567", expected: "+15551234567", national: "+15551234567"},
+
+		{input: "+988123456789", wantErr: true},     // Iranian landline
+		{input: "+97141234567", wantErr: true},      // UAE landline
+		{input: "+0123456789", wantErr: true},       // calling codes never start with 0
+		{input: "+1234", wantErr: true},             // too short
+		{input: "+1234567890123456", wantErr: true}, // too long
+		{input: "5551234567", wantErr: true},        // no country code
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			p, err := otp.NewPhoneNumber(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error for %q, got %q", tt.input, p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error for %q: %v", tt.input, err)
+			}
+			if p != tt.expected || p.National() != tt.national {
+				t.Fatalf("expected %q/%q, got %q/%q", tt.expected, tt.national, p, p.National())
+			}
+		})
+	}
+}
+
+func TestService_SendAndVerify_AcrossPhoneFormats(t *testing.T) {
+	ttl, _ := otp.NewCodeTTL(time.Minute)
+	svc := otp.NewService(otp.ServiceConfig{
+		TTL:     ttl,
+		Hasher:  newTestHasher(t),
+		CodeGen: func() (string, error) { return "123456", nil },
+	})
+
+	formats := []string{"09123456789", "9123456789", "+989123456789"}
+	for _, sendForm := range formats {
+		sendPhone, err := otp.NewIranPhoneNumber(sendForm)
+		if err != nil {
+			t.Fatalf("parse %q: %v", sendForm, err)
+		}
+		o, err := svc.NewOTP("b1", sendPhone.PhoneNumber(), otp.Policy{})
+		if err != nil {
+			t.Fatalf("new otp for %q: %v", sendForm, err)
+		}
+
+		for _, verifyForm := range formats {
+			verifyPhone, _ := otp.NewIranPhoneNumber(verifyForm)
+			ok, err := svc.Verify(o, "b1", verifyPhone.PhoneNumber(), "123456", otp.Policy{})
+			if err != nil || !ok {
+				t.Fatalf("sent to %q, verify as %q: ok=%v err=%v", sendForm, verifyForm, ok, err)
+			}
+		}
+	}
+}
+
 func Test_NewIranPhoneNumber(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -112,13 +339,13 @@ func Test_NewIranPhoneNumber(t *testing.T) {
 		{
 			name:        "valid 10-digit number starting with 9",
 			input:       "9123456789",
-			expected:    otp.IranPhoneNumber("9123456789"),
+			expected:    otp.IranPhoneNumber("+989123456789"),
 			expectError: false,
 		},
 		{
 			name:        "valid 11-digit number with 0 prefix",
 			input:       "09123456789",
-			expected:    otp.IranPhoneNumber("09123456789"),
+			expected:    otp.IranPhoneNumber("+989123456789"),
 			expectError: false,
 		},
 		{
@@ -130,19 +357,19 @@ func Test_NewIranPhoneNumber(t *testing.T) {
 		{
 			name:        "valid another 10-digit number",
 			input:       "9876543210",
-			expected:    otp.IranPhoneNumber("9876543210"),
+			expected:    otp.IranPhoneNumber("+989876543210"),
 			expectError: false,
 		},
 		{
 			name:        "valid minimum 10-digit number",
 			input:       "9000000000",
-			expected:    otp.IranPhoneNumber("9000000000"),
+			expected:    otp.IranPhoneNumber("+989000000000"),
 			expectError: false,
 		},
 		{
 			name:        "valid maximum 10-digit number",
 			input:       "9999999999",
-			expected:    otp.IranPhoneNumber("9999999999"),
+			expected:    otp.IranPhoneNumber("+989999999999"),
 			expectError: false,
 		},
 
@@ -258,3 +485,110 @@ func Test_NewIranPhoneNumber(t *testing.T) {
 		})
 	}
 }
+
+func Test_NewCodeFormat(t *testing.T) {
+	if _, err := otp.NewCodeFormat(8, otp.CodeCharsetAlphanumeric); err != nil {
+		t.Fatalf("expected No Error for valid format, got %v", err)
+	}
+	if _, err := otp.NewCodeFormat(3, otp.CodeCharsetNumeric); err != otp.ErrInvalidCodeFormat {
+		t.Fatalf("expected ErrInvalidCodeFormat for short code, got %v", err)
+	}
+	if _, err := otp.NewCodeFormat(6, "hex"); err != otp.ErrInvalidCodeFormat {
+		t.Fatalf("expected ErrInvalidCodeFormat for unknown charset, got %v", err)
+	}
+}
+
+func TestService_NewOTP_HonorsPolicyCodeFormat(t *testing.T) {
+	ttl, _ := otp.NewCodeTTL(time.Minute)
+	svc := otp.NewService(otp.ServiceConfig{TTL: ttl, Hasher: newTestHasher(t)})
+
+	o, err := svc.NewOTP("b1", "+989123456789", otp.Policy{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := otp.ValidateCode(o.Code, otp.DefaultCodeFormat); err != nil {
+		t.Fatalf("expected default 6-digit code, got %q", o.Code)
+	}
+
+	short, _ := otp.NewCodeFormat(4, otp.CodeCharsetNumeric)
+	long, _ := otp.NewCodeFormat(8, otp.CodeCharsetAlphanumeric)
+	for _, format := range []otp.CodeFormat{short, long} {
+		policy := otp.Policy{CodeFormat: format}
+		o, err := svc.NewOTP("b1", "+989123456789", policy)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := otp.ValidateCode(o.Code, format); err != nil {
+			t.Fatalf("code %q does not match %+v", o.Code, format)
+		}
+		ok, err := svc.Verify(o, "b1", "+989123456789", o.Code, policy)
+		if err != nil || !ok {
+			t.Fatalf("expected verify success for %+v, got ok=%v err=%v", format, ok, err)
+		}
+	}
+}
+
+func TestService_Verify_RejectsCodeOutsidePolicyFormat(t *testing.T) {
+	ttl, _ := otp.NewCodeTTL(time.Minute)
+	svc := otp.NewService(otp.ServiceConfig{TTL: ttl, Hasher: newTestHasher(t)})
+	short, _ := otp.NewCodeFormat(4, otp.CodeCharsetNumeric)
+
+	if _, err := svc.Verify(otp.OTP{}, "b1", "+989123456789", "123456", otp.Policy{CodeFormat: short}); err != otp.ErrInvalidCode {
+		t.Fatalf("expected ErrInvalidCode, got %v", err)
+	}
+	if _, err := svc.Verify(otp.OTP{}, "b1", "+989123456789", "ab12cd34", otp.Policy{}); err != otp.ErrInvalidCode {
+		t.Fatalf("expected ErrInvalidCode, got %v", err)
+	}
+}
+
+func TestService_NewOTP_HonorsPolicyTTL(t *testing.T) {
+	now := time.Unix(100, 0)
+	defaultTTL, _ := otp.NewCodeTTL(2 * time.Minute)
+	svc := otp.NewService(otp.ServiceConfig{
+		Now:    func() time.Time { return now },
+		TTL:    defaultTTL,
+		Hasher: newTestHasher(t),
+	})
+
+	o, err := svc.NewOTP("b1", "+989123456789", otp.Policy{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !o.ExpiresAt.Equal(now.Add(2 * time.Minute)) {
+		t.Fatalf("expected default ttl, got ExpiresAt %v", o.ExpiresAt)
+	}
+
+	businessTTL, _ := otp.NewCodeTTL(30 * time.Second)
+	o, err = svc.NewOTP("b1", "+989123456789", otp.Policy{TTL: businessTTL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !o.ExpiresAt.Equal(now.Add(30 * time.Second)) {
+		t.Fatalf("expected business ttl, got ExpiresAt %v", o.ExpiresAt)
+	}
+}
+
+func TestService_SuspendedPolicyBlocksSendAndVerify(t *testing.T) {
+	ttl, _ := otp.NewCodeTTL(time.Minute)
+	svc := otp.NewService(otp.ServiceConfig{
+		TTL:     ttl,
+		Hasher:  newTestHasher(t),
+		CodeGen: func() (string, error) { return "123456", nil },
+	})
+
+	pending, err := svc.NewOTP("b1", "+989123456789", otp.Policy{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	suspended := otp.Policy{Suspended: true}
+	if _, err := svc.NewOTP("b1", "+989123456789", suspended); err != otp.ErrBusinessSuspended {
+		t.Fatalf("expected ErrBusinessSuspended on send, got %v", err)
+	}
+	if ok, err := svc.Verify(pending, "b1", "+989123456789", "123456", suspended); ok || err != otp.ErrBusinessSuspended {
+		t.Fatalf("expected pending OTP to be unverifiable, got ok=%v err=%v", ok, err)
+	}
+	if ok, err := svc.Verify(pending, "b1", "+989123456789", "123456", otp.Policy{}); !ok || err != nil {
+		t.Fatalf("expected pending OTP to verify after reactivation, got ok=%v err=%v", ok, err)
+	}
+}
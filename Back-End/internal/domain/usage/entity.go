@@ -0,0 +1,92 @@
+package usage
+
+import "time"
+
+// Event is a billable or reportable OTP outcome counted per business per day.
+type Event string
+
+const (
+	EventSend            Event = "sends"
+	EventVerifySucceeded Event = "verify_succeeded"
+	EventVerifyFailed    Event = "verify_failed"
+	// EventExpired is a verification attempt that found no pending code: it expired,
+	// or was already used. It is reported but not counted in the daily counters.
+	EventExpired Event = "expired"
+)
+
+// Granularity is the bucket size of a usage report.
+type Granularity string
+
+const (
+	GranularityDay   Granularity = "day"
+	GranularityMonth Granularity = "month"
+)
+
+func NewGranularity(v string) (Granularity, error) {
+	switch g := Granularity(v); g {
+	case GranularityDay, GranularityMonth:
+		return g, nil
+	}
+	return "", ErrInvalidGranularity
+}
+
+// Truncate returns the start of the bucket containing t.
+func (g Granularity) Truncate(t time.Time) time.Time {
+	if g == GranularityMonth {
+		return MonthStart(t)
+	}
+	return Day(t)
+}
+
+func (g Granularity) next(t time.Time) time.Time {
+	if g == GranularityMonth {
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// ReportQuery selects one business's events in [From, To), bucketed by Granularity.
+// From and To are aligned to bucket boundaries by Service.NewReportQuery.
+type ReportQuery struct {
+	BusinessID  string
+	From        time.Time
+	To          time.Time
+	Granularity Granularity
+}
+
+// Summary is one bucket of a usage report, starting at Period.
+type Summary struct {
+	Period          time.Time
+	Sends           int64
+	VerifySucceeded int64
+	VerifyFailed    int64
+	Expired         int64
+}
+
+// Daily is one business's usage for one UTC day.
+type Daily struct {
+	BusinessID      string
+	Day             time.Time
+	Sends           int64
+	VerifySucceeded int64
+	VerifyFailed    int64
+}
+
+// Plan is what a business pays for. A zero MonthlySendQuota means unlimited.
+type Plan struct {
+	ID               string
+	Name             string
+	MonthlySendQuota int64
+}
+
+// Day truncates t to the start of its UTC day, the unit usage is counted in.
+func Day(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// MonthStart is the start of t's UTC month, when monthly quotas reset.
+func MonthStart(t time.Time) time.Time {
+	y, m, _ := t.UTC().Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,10 @@
+package usage
+
+import "errors"
+
+var (
+	ErrPlanNotFound       = errors.New("usage: plan not found")
+	ErrInvalidBusiness    = errors.New("usage: invalid business id")
+	ErrInvalidGranularity = errors.New("usage: invalid granularity")
+	ErrInvalidRange       = errors.New("usage: invalid report range")
+)
@@ -0,0 +1,34 @@
+package usage
+
+import (
+	"context"
+	"time"
+)
+
+// Counter holds the live per-day counters. It is the source of truth for the current
+// month's quota; Store only receives flushed copies.
+type Counter interface {
+	Incr(ctx context.Context, businessID string, event Event, at time.Time) error
+	// MonthSends returns the sends counted in at's UTC month.
+	MonthSends(ctx context.Context, businessID string, at time.Time) (int64, error)
+	// PopDirty returns up to n business-days whose counters changed since they were
+	// last popped, with their current totals.
+	PopDirty(ctx context.Context, n int) ([]Daily, error)
+	// MarkDirty re-queues business-days whose flush failed.
+	MarkDirty(ctx context.Context, days []Daily) error
+}
+
+// EventStore keeps one row per send and verification outcome for reporting.
+type EventStore interface {
+	Record(ctx context.Context, businessID string, event Event, at time.Time) error
+	// Summarize returns the non-empty buckets of q in chronological order.
+	Summarize(ctx context.Context, q ReportQuery) ([]Summary, error)
+}
+
+type Store interface {
+	// UpsertDaily writes absolute daily totals, so flushing the same day twice is harmless.
+	UpsertDaily(ctx context.Context, days []Daily) error
+	GetPlan(ctx context.Context, id string) (Plan, error)
+	// MonthlySendQuota returns the quota of the business's plan; 0 means unlimited.
+	MonthlySendQuota(ctx context.Context, businessID string) (int64, error)
+}
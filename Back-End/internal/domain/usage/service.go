@@ -0,0 +1,91 @@
+package usage
+
+import (
+	"strings"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+)
+
+// Reports are capped so a single request can't scan years of events day by day.
+const (
+	MaxReportDays   = 366
+	MaxReportMonths = 36
+)
+
+type Service struct {
+	now func() time.Time
+}
+
+type ServiceConfig struct {
+	Now func() time.Time
+}
+
+func NewService(cfg ServiceConfig) *Service {
+	now := cfg.Now
+	if now == nil {
+		now = time.Now
+	}
+	return &Service{now: now}
+}
+
+func (s *Service) Now() time.Time {
+	return s.now()
+}
+
+// CheckQuota returns an *otp.RateLimitError with reason monthly_quota once sent has
+// reached quota, retryable when the next UTC month starts. A zero quota is unlimited.
+func (s *Service) CheckQuota(sent, quota int64) error {
+	if quota <= 0 || sent < quota {
+		return nil
+	}
+	now := s.now()
+	return &otp.RateLimitError{
+		Reason:     otp.RateLimitReasonMonthlyQuota,
+		RetryAfter: MonthStart(now).AddDate(0, 1, 0).Sub(now),
+	}
+}
+
+// NewReportQuery builds the report for the UTC days from..to, both inclusive. The range
+// is widened to whole months for monthly reports.
+func (s *Service) NewReportQuery(businessID string, from, to time.Time, granularity Granularity) (ReportQuery, error) {
+	if strings.TrimSpace(businessID) == "" {
+		return ReportQuery{}, ErrInvalidBusiness
+	}
+	if _, err := NewGranularity(string(granularity)); err != nil {
+		return ReportQuery{}, err
+	}
+	from = granularity.Truncate(from)
+	to = granularity.next(granularity.Truncate(to))
+	if !from.Before(to) {
+		return ReportQuery{}, ErrInvalidRange
+	}
+
+	maxBuckets := MaxReportDays
+	if granularity == GranularityMonth {
+		maxBuckets = MaxReportMonths
+	}
+	buckets := 0
+	for t := from; t.Before(to); t = granularity.next(t) {
+		if buckets++; buckets > maxBuckets {
+			return ReportQuery{}, ErrInvalidRange
+		}
+	}
+	return ReportQuery{BusinessID: businessID, From: from, To: to, Granularity: granularity}, nil
+}
+
+// Fill returns one summary per bucket of q, using zero counts where rows has none.
+func Fill(q ReportQuery, rows []Summary) []Summary {
+	byPeriod := make(map[time.Time]Summary, len(rows))
+	for _, r := range rows {
+		byPeriod[q.Granularity.Truncate(r.Period)] = r
+	}
+
+	var out []Summary
+	for t := q.From; t.Before(q.To); t = q.Granularity.next(t) {
+		s := byPeriod[t]
+		s.Period = t
+		out = append(out, s)
+	}
+	return out
+}
@@ -0,0 +1,78 @@
+package usage_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+	"github.com/panbeh/otp-backend/internal/domain/usage"
+)
+
+func TestService_CheckQuota(t *testing.T) {
+	now := time.Date(2026, 2, 27, 12, 0, 0, 0, time.UTC)
+	svc := usage.NewService(usage.ServiceConfig{Now: func() time.Time { return now }})
+
+	if err := svc.CheckQuota(99, 100); err != nil {
+		t.Fatalf("expected send under quota to pass, got %v", err)
+	}
+	if err := svc.CheckQuota(1_000_000, 0); err != nil {
+		t.Fatalf("expected zero quota to be unlimited, got %v", err)
+	}
+
+	err := svc.CheckQuota(100, 100)
+	if !errors.Is(err, otp.ErrQuotaExceeded) || !errors.Is(err, otp.ErrRateLimited) {
+		t.Fatalf("expected quota error, got %v", err)
+	}
+	var rl *otp.RateLimitError
+	if !errors.As(err, &rl) || rl.Reason != otp.RateLimitReasonMonthlyQuota {
+		t.Fatalf("expected monthly_quota reason, got %#v", err)
+	}
+	if rl.RetryAfter != 36*time.Hour {
+		t.Fatalf("expected retry at the start of March, got %v", rl.RetryAfter)
+	}
+}
+
+func TestRateLimitError_OnlyQuotaMatchesErrQuotaExceeded(t *testing.T) {
+	err := &otp.RateLimitError{Reason: otp.RateLimitReasonCooldown}
+	if errors.Is(err, otp.ErrQuotaExceeded) {
+		t.Fatal("expected per-phone cooldown not to count as quota exceeded")
+	}
+}
+
+func TestDayAndMonthStart(t *testing.T) {
+	at := time.Date(2026, 3, 15, 23, 30, 0, 0, time.FixedZone("IRST", 3*3600+1800))
+	if got := usage.Day(at); !got.Equal(time.Date(2026, 3, 15, 20, 0, 0, 0, time.UTC).Truncate(24 * time.Hour)) {
+		t.Fatalf("unexpected day: %v", got)
+	}
+	if got := usage.MonthStart(at); !got.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected month start: %v", got)
+	}
+}
+
+func TestService_NewReportQuery(t *testing.T) {
+	svc := usage.NewService(usage.ServiceConfig{})
+	from := time.Date(2026, 1, 15, 8, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	q, err := svc.NewReportQuery("b1", from, to, usage.GranularityMonth)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if !q.From.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) || !q.To.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected whole months, got %v..%v", q.From, q.To)
+	}
+	if got := usage.Fill(q, nil); len(got) != 3 {
+		t.Fatalf("expected 3 monthly buckets, got %d", len(got))
+	}
+
+	if _, err := svc.NewReportQuery("b1", to, from, usage.GranularityDay); !errors.Is(err, usage.ErrInvalidRange) {
+		t.Fatalf("expected reversed range to fail, got %v", err)
+	}
+	if _, err := svc.NewReportQuery("b1", from, from.AddDate(0, 0, usage.MaxReportDays), usage.GranularityDay); !errors.Is(err, usage.ErrInvalidRange) {
+		t.Fatalf("expected range over the daily cap to fail, got %v", err)
+	}
+	if _, err := svc.NewReportQuery("b1", from, to, "week"); !errors.Is(err, usage.ErrInvalidGranularity) {
+		t.Fatalf("expected unknown granularity to fail, got %v", err)
+	}
+}
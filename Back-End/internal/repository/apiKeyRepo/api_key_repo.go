@@ -0,0 +1,146 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/apikey"
+	"github.com/panbeh/otp-backend/internal/domain/business"
+)
+
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) apikey.Repository {
+	return &APIKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, business_id, name, key_hash, key_prefix, scopes, created_at, last_used_at, revoked_at`
+
+func (r *APIKeyRepository) Create(ctx context.Context, k apikey.APIKey) error {
+	// Only the hash is stored; the raw key is handed back to the caller once.
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO api_keys (`+apiKeyColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, k.ID, k.BusinessID, k.Name, k.KeyHash, k.KeyPrefix, joinScopes(k.Scopes), k.CreatedAt, k.LastUsedAt, k.RevokedAt)
+	return err
+}
+
+func (r *APIKeyRepository) GetByKey(ctx context.Context, key string) (apikey.APIKey, error) {
+	return r.scanOne(r.db.QueryRowContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE key_hash = $1
+	`, business.HashToken(key)))
+}
+
+func (r *APIKeyRepository) GetByID(ctx context.Context, businessID, id string) (apikey.APIKey, error) {
+	return r.scanOne(r.db.QueryRowContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE id = $1 AND business_id = $2
+	`, id, businessID))
+}
+
+func (r *APIKeyRepository) ListByBusiness(ctx context.Context, businessID string) ([]apikey.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+apiKeyColumns+`
+		FROM api_keys
+		WHERE business_id = $1
+		ORDER BY created_at
+	`, businessID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []apikey.APIKey
+	for rows.Next() {
+		k, err := r.scanOne(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func (r *APIKeyRepository) Revoke(ctx context.Context, businessID, id string, at time.Time) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE api_keys
+		SET revoked_at = $3
+		WHERE id = $1 AND business_id = $2 AND revoked_at IS NULL
+	`, id, businessID, at)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return apikey.ErrNotFound
+	}
+	return nil
+}
+
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE api_keys
+		SET last_used_at = $2
+		WHERE id = $1
+	`, id, at)
+	return err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (r *APIKeyRepository) scanOne(row scanner) (apikey.APIKey, error) {
+	var (
+		k          apikey.APIKey
+		scopes     string
+		lastUsedAt sql.NullTime
+		revokedAt  sql.NullTime
+	)
+	err := row.Scan(&k.ID, &k.BusinessID, &k.Name, &k.KeyHash, &k.KeyPrefix, &scopes, &k.CreatedAt, &lastUsedAt, &revokedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return apikey.APIKey{}, apikey.ErrNotFound
+		}
+		return apikey.APIKey{}, err
+	}
+	k.Scopes = splitScopes(scopes)
+	if lastUsedAt.Valid {
+		k.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		k.RevokedAt = &revokedAt.Time
+	}
+	return k, nil
+}
+
+// Scopes are stored as a comma-separated list, like business country codes.
+func joinScopes(scopes []apikey.Scope) string {
+	parts := make([]string, len(scopes))
+	for i, s := range scopes {
+		parts[i] = string(s)
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitScopes(s string) []apikey.Scope {
+	if s == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	scopes := make([]apikey.Scope, len(parts))
+	for i, p := range parts {
+		scopes[i] = apikey.Scope(p)
+	}
+	return scopes
+}
@@ -3,6 +3,9 @@ package business
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strings"
+	"time"
 
 	"github.com/panbeh/otp-backend/internal/domain/business"
 )
@@ -15,30 +18,220 @@ func NewBusinessRepository(db *sql.DB) business.Repository {
 	return &BusinessRepository{db: db}
 }
 
+const businessColumns = `id, name, status, token_hash, token_prefix, created_at, deleted_at, allowed_country_codes,
+	code_length, code_charset, otp_ttl_seconds, previous_token_hash, previous_token_expires_at, plan_id`
+
 func (r *BusinessRepository) Create(ctx context.Context, b business.Business) (business.Business, error) {
 	// ID/CreatedAt are generated in the domain service; repository persists them as-is.
+	// Only the token hash is stored; the raw token is handed back to the caller once.
 	_, err := r.db.ExecContext(ctx, `
-		INSERT INTO businesses (id, name, token, created_at)
-		VALUES ($1, $2, $3, $4)
-	`, b.ID, b.Name, b.Token, b.CreatedAt)
+		INSERT INTO businesses (id, name, status, token_hash, token_prefix, created_at, allowed_country_codes, code_length, code_charset, otp_ttl_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, b.ID, b.Name, b.Status, b.TokenHash, b.TokenPrefix, b.CreatedAt, joinCodes(b.AllowedCountries), b.CodeLength, b.CodeCharset, ttlSeconds(b.OTPTTL))
 	if err != nil {
 		return business.Business{}, err
 	}
 	return b, nil
 }
 
+func (r *BusinessRepository) GetByID(ctx context.Context, id string) (business.Business, error) {
+	return r.scanOne(r.db.QueryRowContext(ctx, `
+		SELECT `+businessColumns+`
+		FROM businesses
+		WHERE id = $1
+	`, id))
+}
+
 func (r *BusinessRepository) GetByToken(ctx context.Context, token string) (business.Business, error) {
-	var b business.Business
+	return r.scanOne(r.db.QueryRowContext(ctx, `
+		SELECT `+businessColumns+`
+		FROM businesses
+		WHERE (token_hash = $1 OR (previous_token_hash = $1 AND previous_token_expires_at > now()))
+		  AND deleted_at IS NULL
+	`, business.HashToken(token)))
+}
+
+func (r *BusinessRepository) List(ctx context.Context, filter business.ListFilter) ([]business.Business, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	// An empty status lists everything that isn't deleted.
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+businessColumns+`
+		FROM businesses
+		WHERE ($1 = '' AND status <> 'deleted') OR status = $1
+		ORDER BY created_at, id
+		LIMIT $2 OFFSET $3
+	`, string(filter.Status), limit, filter.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []business.Business
+	for rows.Next() {
+		b, err := r.scanOne(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+func (r *BusinessRepository) UpdateName(ctx context.Context, id string, name string) error {
+	return r.exec(ctx, `
+		UPDATE businesses
+		SET name = $2
+		WHERE id = $1 AND deleted_at IS NULL
+	`, id, name)
+}
+
+func (r *BusinessRepository) UpdateStatus(ctx context.Context, b business.Business) error {
+	return r.exec(ctx, `
+		UPDATE businesses
+		SET status = $2, deleted_at = $3
+		WHERE id = $1
+	`, b.ID, b.Status, b.DeletedAt)
+}
+
+func (r *BusinessRepository) RotateToken(ctx context.Context, b business.Business, currentTokenHash string) error {
+	// Guarding on the current token makes concurrent rotations fail instead of
+	// silently dropping one of the issued tokens.
+	err := r.exec(ctx, `
+		UPDATE businesses
+		SET token_hash = $2, token_prefix = $3, previous_token_hash = $4, previous_token_expires_at = $5
+		WHERE id = $1 AND token_hash = $6
+	`, b.ID, b.TokenHash, b.TokenPrefix, b.PreviousTokenHash, nullTime(b.PreviousTokenExpiresAt), currentTokenHash)
+	if errors.Is(err, business.ErrNotFound) {
+		return business.ErrTokenConflict
+	}
+	return err
+}
+
+func (r *BusinessRepository) UpdateAllowedCountries(ctx context.Context, id string, codes []string) error {
+	return r.exec(ctx, `
+		UPDATE businesses
+		SET allowed_country_codes = $2
+		WHERE id = $1
+	`, id, joinCodes(codes))
+}
+
+// UpdateCodeFormat stores the business's OTP code format; 0 and "" mean the default.
+func (r *BusinessRepository) UpdateCodeFormat(ctx context.Context, id string, length int, charset string) error {
+	return r.exec(ctx, `
+		UPDATE businesses
+		SET code_length = $2, code_charset = $3
+		WHERE id = $1
+	`, id, length, charset)
+}
+
+// UpdateOTPTTL stores the business's OTP expiry override; 0 means the default.
+func (r *BusinessRepository) UpdateOTPTTL(ctx context.Context, id string, ttl time.Duration) error {
+	return r.exec(ctx, `
+		UPDATE businesses
+		SET otp_ttl_seconds = $2
+		WHERE id = $1
+	`, id, ttlSeconds(ttl))
+}
+
+func (r *BusinessRepository) UpdatePlan(ctx context.Context, id string, planID string) error {
+	return r.exec(ctx, `
+		UPDATE businesses
+		SET plan_id = $2
+		WHERE id = $1 AND deleted_at IS NULL
+	`, id, sql.NullString{String: planID, Valid: planID != ""})
+}
+
+func (r *BusinessRepository) SetSigningSecret(ctx context.Context, id string, s business.SigningSecret) error {
+	return r.exec(ctx, `
+		UPDATE businesses
+		SET signing_secret_sealed = $2, signing_key_id = $3
+		WHERE id = $1
+	`, id, s.Sealed, s.KeyID)
+}
+
+func (r *BusinessRepository) GetSigningSecret(ctx context.Context, id string) (business.SigningSecret, error) {
+	var s business.SigningSecret
 	err := r.db.QueryRowContext(ctx, `
-		SELECT id, name, token, created_at
+		SELECT signing_secret_sealed, signing_key_id
 		FROM businesses
-		WHERE token = $1
-	`, token).Scan(&b.ID, &b.Name, &b.Token, &b.CreatedAt)
+		WHERE id = $1
+	`, id).Scan(&s.Sealed, &s.KeyID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return business.SigningSecret{}, business.ErrNotFound
+		}
+		return business.SigningSecret{}, err
+	}
+	return s, nil
+}
+
+// OTP TTLs are stored in whole seconds; sub-second overrides are not meaningful for SMS codes.
+func ttlSeconds(ttl time.Duration) int64 {
+	return int64(ttl / time.Second)
+}
+
+// exec runs an update and maps "no row touched" to business.ErrNotFound.
+func (r *BusinessRepository) exec(ctx context.Context, query string, args ...any) error {
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return business.ErrNotFound
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (r *BusinessRepository) scanOne(row scanner) (business.Business, error) {
+	var (
+		b                 business.Business
+		deletedAt         sql.NullTime
+		codes             string
+		ttl               int64
+		previousExpiresAt sql.NullTime
+		planID            sql.NullString
+	)
+	err := row.Scan(&b.ID, &b.Name, &b.Status, &b.TokenHash, &b.TokenPrefix, &b.CreatedAt, &deletedAt, &codes,
+		&b.CodeLength, &b.CodeCharset, &ttl, &b.PreviousTokenHash, &previousExpiresAt, &planID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return business.Business{}, business.ErrNotFound
 		}
 		return business.Business{}, err
 	}
+	if deletedAt.Valid {
+		b.DeletedAt = &deletedAt.Time
+	}
+	b.AllowedCountries = splitCodes(codes)
+	b.OTPTTL = time.Duration(ttl) * time.Second
+	b.PreviousTokenExpiresAt = previousExpiresAt.Time
+	b.PlanID = planID.String
 	return b, nil
 }
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// Country codes are stored as a comma-separated list; an empty string means "use the default".
+func joinCodes(codes []string) string {
+	return strings.Join(codes, ",")
+}
+
+func splitCodes(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
@@ -0,0 +1,57 @@
+package databases_test
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/panbeh/otp-backend/internal/repository/databases"
+)
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	// NewMigrator loads the embedded files without touching the database.
+	if _, err := databases.NewMigrator(nil); err != nil {
+		t.Fatalf("embedded migrations are invalid: %v", err)
+	}
+}
+
+func TestLoadMigrations_OrdersAndPairs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0002_add_b.up.sql":    {Data: []byte("ALTER TABLE a ADD COLUMN b TEXT;")},
+		"m/0002_add_b.down.sql":  {Data: []byte("ALTER TABLE a DROP COLUMN b;")},
+		"m/0001_create.up.sql":   {Data: []byte("CREATE TABLE a (id TEXT);")},
+		"m/0001_create.down.sql": {Data: []byte("DROP TABLE a;")},
+		"m/README.md":            {Data: []byte("not a migration")},
+	}
+	got, err := databases.LoadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(got) != 2 || got[0].Version != 1 || got[0].Name != "create" || got[1].Version != 2 || got[1].Down == "" {
+		t.Fatalf("unexpected migrations: %#v", got)
+	}
+}
+
+func TestLoadMigrations_RejectsBadFiles(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"missing down": {
+			"m/0001_create.up.sql": {Data: []byte("CREATE TABLE a (id TEXT);")},
+		},
+		"bad version": {
+			"m/first_create.up.sql":   {Data: []byte("SELECT 1;")},
+			"m/first_create.down.sql": {Data: []byte("SELECT 1;")},
+		},
+		"bad direction": {
+			"m/0001_create.sideways.sql": {Data: []byte("SELECT 1;")},
+		},
+		"name mismatch": {
+			"m/0001_create.up.sql":  {Data: []byte("SELECT 1;")},
+			"m/0001_other.down.sql": {Data: []byte("SELECT 1;")},
+		},
+	}
+	for name, fsys := range cases {
+		if _, err := databases.LoadMigrations(fsys, "m"); !errors.Is(err, databases.ErrInvalidMigration) {
+			t.Fatalf("%s: expected ErrInvalidMigration, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+-- The original businesses table. IF NOT EXISTS lets databases that were set up by
+-- hand before migrations existed adopt this history.
+CREATE TABLE IF NOT EXISTS businesses (
+    id         TEXT PRIMARY KEY,
+    name       TEXT NOT NULL,
+    token      TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL
+);
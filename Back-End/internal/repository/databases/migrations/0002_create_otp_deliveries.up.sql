@@ -0,0 +1,18 @@
+CREATE TABLE otp_deliveries (
+    id                  TEXT PRIMARY KEY,
+    business_id         TEXT NOT NULL REFERENCES businesses (id),
+    masked_phone        TEXT NOT NULL,
+    status              TEXT NOT NULL,
+    provider            TEXT NOT NULL DEFAULT '',
+    provider_message_id TEXT NOT NULL DEFAULT '',
+    failure_reason      TEXT NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ NOT NULL,
+    updated_at          TIMESTAMPTZ NOT NULL,
+    sent_at             TIMESTAMPTZ,
+    delivered_at        TIMESTAMPTZ
+);
+
+-- Delivery-report webhooks look deliveries up by the gateway's message id.
+CREATE INDEX otp_deliveries_provider_message_idx
+    ON otp_deliveries (provider, provider_message_id)
+    WHERE provider_message_id <> '';
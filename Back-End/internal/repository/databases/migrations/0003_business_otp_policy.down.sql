@@ -0,0 +1,5 @@
+ALTER TABLE businesses
+    DROP COLUMN IF EXISTS allowed_country_codes,
+    DROP COLUMN IF EXISTS code_length,
+    DROP COLUMN IF EXISTS code_charset,
+    DROP COLUMN IF EXISTS otp_ttl_seconds;
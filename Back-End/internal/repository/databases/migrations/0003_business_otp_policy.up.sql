@@ -0,0 +1,6 @@
+-- Zero values mean "use the deployment default".
+ALTER TABLE businesses
+    ADD COLUMN allowed_country_codes TEXT NOT NULL DEFAULT '',
+    ADD COLUMN code_length           INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN code_charset          TEXT NOT NULL DEFAULT '',
+    ADD COLUMN otp_ttl_seconds       BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,9 @@
+-- Raw tokens cannot be recovered from their hashes. Rolling back leaves every
+-- business without a token; new ones have to be issued.
+ALTER TABLE businesses ADD COLUMN token TEXT UNIQUE;
+
+ALTER TABLE businesses
+    DROP COLUMN IF EXISTS token_hash,
+    DROP COLUMN IF EXISTS token_prefix,
+    DROP COLUMN IF EXISTS previous_token_hash,
+    DROP COLUMN IF EXISTS previous_token_expires_at;
@@ -0,0 +1,19 @@
+-- Tokens are stored as SHA-256 hex digests; only the first 8 characters stay in clear.
+ALTER TABLE businesses
+    ADD COLUMN token_hash                TEXT,
+    ADD COLUMN token_prefix              TEXT NOT NULL DEFAULT '',
+    ADD COLUMN previous_token_hash       TEXT NOT NULL DEFAULT '',
+    ADD COLUMN previous_token_expires_at TIMESTAMPTZ;
+
+UPDATE businesses
+SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex'),
+    token_prefix = CASE WHEN length(token) > 8 THEN left(token, 8) ELSE '' END;
+
+ALTER TABLE businesses
+    ALTER COLUMN token_hash SET NOT NULL,
+    ADD CONSTRAINT businesses_token_hash_key UNIQUE (token_hash),
+    DROP COLUMN token;
+
+CREATE INDEX businesses_previous_token_hash_idx
+    ON businesses (previous_token_hash)
+    WHERE previous_token_hash <> '';
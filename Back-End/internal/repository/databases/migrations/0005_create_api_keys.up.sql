@@ -0,0 +1,14 @@
+CREATE TABLE api_keys (
+    id           TEXT PRIMARY KEY,
+    business_id  TEXT NOT NULL REFERENCES businesses (id),
+    name         TEXT NOT NULL,
+    key_hash     TEXT NOT NULL UNIQUE,
+    key_prefix   TEXT NOT NULL,
+    -- Comma-separated scopes, e.g. "otp:send,otp:verify".
+    scopes       TEXT NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL,
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX api_keys_business_id_idx ON api_keys (business_id);
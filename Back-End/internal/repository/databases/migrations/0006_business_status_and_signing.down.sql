@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS businesses_status_created_at_idx;
+
+ALTER TABLE businesses
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS signing_secret_sealed;
@@ -0,0 +1,8 @@
+ALTER TABLE businesses
+    ADD COLUMN status                TEXT NOT NULL DEFAULT 'active'
+        CHECK (status IN ('active', 'suspended', 'deleted')),
+    ADD COLUMN deleted_at            TIMESTAMPTZ,
+    -- AES-GCM sealed request-signing secret; empty when the business has none.
+    ADD COLUMN signing_secret_sealed TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX businesses_status_created_at_idx ON businesses (status, created_at, id);
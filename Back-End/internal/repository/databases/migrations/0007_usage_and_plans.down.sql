@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS usage_events;
+DROP TABLE IF EXISTS usage_daily;
+ALTER TABLE businesses DROP COLUMN IF EXISTS plan_id;
+DROP TABLE IF EXISTS plans;
@@ -0,0 +1,31 @@
+CREATE TABLE plans (
+    id                 TEXT PRIMARY KEY,
+    name               TEXT NOT NULL,
+    -- Sends allowed per UTC calendar month; 0 means unlimited.
+    monthly_send_quota BIGINT NOT NULL DEFAULT 0 CHECK (monthly_send_quota >= 0)
+);
+
+-- Businesses without a plan are unmetered.
+ALTER TABLE businesses ADD COLUMN plan_id TEXT REFERENCES plans (id);
+
+-- Daily totals flushed from the Redis counters. Rows are overwritten with absolute
+-- values, so a repeated flush is harmless.
+CREATE TABLE usage_daily (
+    business_id      TEXT NOT NULL REFERENCES businesses (id),
+    day              DATE NOT NULL,
+    sends            BIGINT NOT NULL DEFAULT 0,
+    verify_succeeded BIGINT NOT NULL DEFAULT 0,
+    verify_failed    BIGINT NOT NULL DEFAULT 0,
+    updated_at       TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (business_id, day)
+);
+
+-- One row per send and verification outcome, for GET /usage.
+CREATE TABLE usage_events (
+    id          BIGSERIAL PRIMARY KEY,
+    business_id TEXT NOT NULL REFERENCES businesses (id),
+    event       TEXT NOT NULL,
+    occurred_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX usage_events_business_occurred_idx ON usage_events (business_id, occurred_at);
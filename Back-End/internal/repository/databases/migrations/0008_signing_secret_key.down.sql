@@ -0,0 +1 @@
+ALTER TABLE businesses DROP COLUMN IF EXISTS signing_key_id;
@@ -0,0 +1,3 @@
+-- API key whose scopes signed requests act with; empty for secrets issued before
+-- secrets were tied to a key, which only get the OTP scopes.
+ALTER TABLE businesses ADD COLUMN signing_key_id TEXT NOT NULL DEFAULT '';
@@ -3,24 +3,91 @@ package databases
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/panbeh/otp-backend/internal/config"
 )
 
-func ConnectPostgres(cfg *config.Postgres) *sql.DB {
+const (
+	postgresInitialBackoff = 250 * time.Millisecond
+	postgresMaxBackoff     = 5 * time.Second
+	postgresPingTimeout    = 2 * time.Second
+)
+
+// ConnectPostgres opens the connection pool and waits for Postgres to answer,
+// retrying with exponential backoff for up to cfg.PostgresConnectTimeout so a
+// database that starts slower than the app doesn't crash it. The caller owns the
+// returned pool and closes it on shutdown.
+func ConnectPostgres(ctx context.Context, cfg config.Postgres) (*sql.DB, error) {
 	db, err := sql.Open("pgx", string(cfg.PostgresDSN))
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	defer db.Close()
 
 	db.SetMaxOpenConns(cfg.PostgresMaxOpenConns)
 	db.SetMaxIdleConns(cfg.PostgresMaxIdleConns)
 	db.SetConnMaxLifetime(cfg.PostgresConnMaxLifetime)
 
-	if err := db.PingContext(context.Background()); err != nil {
-		panic(err)
+	ctx, cancel := context.WithTimeout(ctx, cfg.PostgresConnectTimeout)
+	defer cancel()
+
+	backoff := postgresInitialBackoff
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancelPing := context.WithTimeout(ctx, postgresPingTimeout)
+		err = db.PingContext(pingCtx)
+		cancelPing()
+		if err == nil {
+			return db, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			_ = db.Close()
+			return nil, fmt.Errorf("postgres: not reachable after %d attempts: %w", attempt, err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, postgresMaxBackoff)
+	}
+}
+
+// PoolStats is the part of sql.DBStats worth watching for pool exhaustion.
+type PoolStats struct {
+	MaxOpen      int   `json:"max_open"`
+	Open         int   `json:"open"`
+	InUse        int   `json:"in_use"`
+	Idle         int   `json:"idle"`
+	WaitCount    int64 `json:"wait_count"`
+	WaitDuration int64 `json:"wait_duration_ms"`
+}
+
+// PostgresProbe is the readiness check for the Postgres pool.
+type PostgresProbe struct {
+	db *sql.DB
+}
+
+func NewPostgresProbe(db *sql.DB) *PostgresProbe {
+	return &PostgresProbe{db: db}
+}
+
+// Check pings Postgres and returns the pool stats either way, so a failing probe
+// still shows whether the pool is exhausted.
+func (p *PostgresProbe) Check(ctx context.Context) (any, error) {
+	ctx, cancel := context.WithTimeout(ctx, postgresPingTimeout)
+	defer cancel()
+	err := p.db.PingContext(ctx)
+	return p.Stats(), err
+}
+
+func (p *PostgresProbe) Stats() PoolStats {
+	s := p.db.Stats()
+	return PoolStats{
+		MaxOpen:      s.MaxOpenConnections,
+		Open:         s.OpenConnections,
+		InUse:        s.InUse,
+		Idle:         s.Idle,
+		WaitCount:    s.WaitCount,
+		WaitDuration: s.WaitDuration.Milliseconds(),
 	}
-	return db
 }
@@ -1,14 +1,111 @@
 package databases
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+
 	"github.com/panbeh/otp-backend/internal/config"
 	"github.com/redis/go-redis/v9"
 )
 
-func ConnectRedisStandAlone(redisAddr config.RedisAddr) redis.UniversalClient {
-	opt, err := redis.ParseURL(string(redisAddr))
+// ConnectRedis builds the client for the configured mode. Repositories only see
+// redis.UniversalClient, so they work the same against all three; multi-key scripts and
+// transactions rely on hash-tagged keys to stay on one cluster slot.
+func ConnectRedis(cfg config.Redis) (redis.UniversalClient, error) {
+	switch cfg.Mode {
+	case config.RedisModeStandalone:
+		return ConnectRedisStandAlone(cfg)
+	case config.RedisModeSentinel:
+		tlsConfig, err := redisTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    string(cfg.SentinelMaster),
+			SentinelAddrs: nodeAddrs(cfg.SentinelAddrs),
+			Username:      cfg.Username,
+			Password:      cfg.Password,
+			DB:            cfg.DB,
+			TLSConfig:     tlsConfig,
+			DialTimeout:   cfg.DialTimeout,
+			ReadTimeout:   cfg.ReadTimeout,
+			WriteTimeout:  cfg.WriteTimeout,
+			PoolSize:      cfg.PoolSize,
+		}), nil
+	case config.RedisModeCluster:
+		tlsConfig, err := redisTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        nodeAddrs(cfg.ClusterAddrs),
+			Username:     cfg.Username,
+			Password:     cfg.Password,
+			TLSConfig:    tlsConfig,
+			DialTimeout:  cfg.DialTimeout,
+			ReadTimeout:  cfg.ReadTimeout,
+			WriteTimeout: cfg.WriteTimeout,
+			PoolSize:     cfg.PoolSize,
+		}), nil
+	}
+	return nil, fmt.Errorf("unsupported redis mode: %q", cfg.Mode)
+}
+
+// ConnectRedisStandAlone builds the client from the typed config, so a bare host:port
+// and a redis:// URL end up with the same options.
+func ConnectRedisStandAlone(cfg config.Redis) (redis.UniversalClient, error) {
+	tlsConfig, err := redisTLSConfig(cfg.TLS)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	return redis.NewClient(&redis.Options{
+		Addr:         string(cfg.Addr),
+		Username:     cfg.Username,
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		TLSConfig:    tlsConfig,
+		DialTimeout:  cfg.DialTimeout,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		PoolSize:     cfg.PoolSize,
+	}), nil
+}
+
+// redisTLSConfig returns nil when TLS is off, which go-redis reads as plain TCP.
+func redisTLSConfig(cfg config.RedisTLS) (*tls.Config, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis tls ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("redis tls ca: no certificates found in " + cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis tls client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func nodeAddrs(addrs []config.RedisNodeAddr) []string {
+	out := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		out = append(out, string(a))
 	}
-	return redis.NewClient(opt)
+	return out
 }
@@ -0,0 +1,140 @@
+package databases_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/panbeh/otp-backend/internal/config"
+	"github.com/panbeh/otp-backend/internal/repository/databases"
+)
+
+func TestConnectRedisStandAlone_AddressStyles(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	mr.RequireAuth("s3cret")
+
+	for name, cfg := range map[string]config.Redis{
+		"host:port": standaloneConfig(t, mr.Addr(), config.RedisConnection{Password: "s3cret", DB: 3}),
+		"url":       standaloneConfig(t, "redis://:s3cret@"+mr.Addr()+"/3", config.RedisConnection{}),
+	} {
+		client, err := databases.ConnectRedis(cfg)
+		if err != nil {
+			t.Fatalf("%s: connect: %v", name, err)
+		}
+		if err := client.Set(ctx, "k-"+name, "v", 0).Err(); err != nil {
+			t.Fatalf("%s: set: %v", name, err)
+		}
+		_ = client.Close()
+		if got, _ := mr.DB(3).Get("k-" + name); got != "v" {
+			t.Fatalf("%s: expected the key in db 3", name)
+		}
+	}
+}
+
+func TestNewRedisConfig_RejectsConflictingURL(t *testing.T) {
+	_, err := config.NewRedisConfig("standalone", "redis://127.0.0.1:6379/2", "", "", "", config.RedisConnection{DB: 5})
+	if err == nil {
+		t.Fatal("expected an error for a db set differently in the URL and REDIS_DB")
+	}
+}
+
+func TestConnectRedisStandAlone_TLS(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	serverCert, caFile := selfSignedCert(t, dir)
+
+	mr, err := miniredis.RunTLS(&tls.Config{Certificates: []tls.Certificate{serverCert}})
+	if err != nil {
+		t.Fatalf("miniredis tls: %v", err)
+	}
+	t.Cleanup(mr.Close)
+
+	redisTLS, err := config.NewRedisTLS(true, caFile, "", "")
+	if err != nil {
+		t.Fatalf("tls config: %v", err)
+	}
+	cfg := standaloneConfig(t, mr.Addr(), config.RedisConnection{TLS: redisTLS, DialTimeout: time.Second})
+	client, err := databases.ConnectRedis(cfg)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer client.Close()
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Fatalf("ping over tls: %v", err)
+	}
+
+	// Without the CA the self-signed server must be rejected.
+	plain := standaloneConfig(t, "rediss://"+mr.Addr(), config.RedisConnection{DialTimeout: time.Second})
+	untrusted, err := databases.ConnectRedis(plain)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer untrusted.Close()
+	if err := untrusted.Ping(ctx).Err(); err == nil {
+		t.Fatal("expected an unknown CA to be rejected")
+	}
+}
+
+func standaloneConfig(t *testing.T, addr string, conn config.RedisConnection) config.Redis {
+	t.Helper()
+	cfg, err := config.NewRedisConfig("standalone", addr, "", "", "", conn)
+	if err != nil {
+		t.Fatalf("redis config: %v", err)
+	}
+	return cfg
+}
+
+// selfSignedCert returns a certificate for 127.0.0.1 and the path of its PEM, which
+// doubles as the CA file.
+func selfSignedCert(t *testing.T, dir string) (tls.Certificate, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "redis-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("cert: %v", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("key pair: %v", err)
+	}
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, certPEM, 0o600); err != nil {
+		t.Fatalf("write ca: %v", err)
+	}
+	return cert, caFile
+}
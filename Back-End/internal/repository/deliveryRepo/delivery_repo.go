@@ -0,0 +1,97 @@
+package delivery
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/panbeh/otp-backend/internal/domain/delivery"
+)
+
+type DeliveryRepository struct {
+	db *sql.DB
+}
+
+func NewDeliveryRepository(db *sql.DB) delivery.Repository {
+	return &DeliveryRepository{db: db}
+}
+
+const deliveryColumns = `id, business_id, masked_phone, status, provider, provider_message_id, failure_reason, created_at, updated_at, sent_at, delivered_at`
+
+func (r *DeliveryRepository) Create(ctx context.Context, d delivery.Delivery) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO otp_deliveries (`+deliveryColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, d.ID, d.BusinessID, d.MaskedPhone, d.Status, d.Provider, d.ProviderMessageID, d.FailureReason,
+		d.CreatedAt, d.UpdatedAt, d.SentAt, d.DeliveredAt)
+	return err
+}
+
+func (r *DeliveryRepository) Update(ctx context.Context, d delivery.Delivery, from delivery.Status) error {
+	// Guarding on the status read before the change makes a concurrent transition,
+	// such as a delivery report racing the send it refers to, fail instead of being
+	// overwritten.
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE otp_deliveries
+		SET status = $2, provider = $3, provider_message_id = $4, failure_reason = $5,
+		    updated_at = $6, sent_at = $7, delivered_at = $8
+		WHERE id = $1 AND status = $9
+	`, d.ID, d.Status, d.Provider, d.ProviderMessageID, d.FailureReason, d.UpdatedAt, d.SentAt, d.DeliveredAt, from)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return delivery.ErrStatusConflict
+	}
+	return nil
+}
+
+func (r *DeliveryRepository) Get(ctx context.Context, businessID, id string) (delivery.Delivery, error) {
+	return r.scanOne(r.db.QueryRowContext(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM otp_deliveries
+		WHERE id = $1 AND business_id = $2
+	`, id, businessID))
+}
+
+func (r *DeliveryRepository) GetByID(ctx context.Context, id string) (delivery.Delivery, error) {
+	return r.scanOne(r.db.QueryRowContext(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM otp_deliveries
+		WHERE id = $1
+	`, id))
+}
+
+func (r *DeliveryRepository) GetByProviderMessageID(ctx context.Context, provider, messageID string) (delivery.Delivery, error) {
+	return r.scanOne(r.db.QueryRowContext(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM otp_deliveries
+		WHERE provider = $1 AND provider_message_id = $2
+	`, provider, messageID))
+}
+
+func (r *DeliveryRepository) scanOne(row *sql.Row) (delivery.Delivery, error) {
+	var (
+		d           delivery.Delivery
+		sentAt      sql.NullTime
+		deliveredAt sql.NullTime
+	)
+	err := row.Scan(&d.ID, &d.BusinessID, &d.MaskedPhone, &d.Status, &d.Provider, &d.ProviderMessageID,
+		&d.FailureReason, &d.CreatedAt, &d.UpdatedAt, &sentAt, &deliveredAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return delivery.Delivery{}, delivery.ErrNotFound
+		}
+		return delivery.Delivery{}, err
+	}
+	if sentAt.Valid {
+		d.SentAt = &sentAt.Time
+	}
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return d, nil
+}
@@ -0,0 +1,27 @@
+package nonce
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// NonceRepository remembers request nonces so signed requests can't be replayed.
+type NonceRepository struct {
+	client redis.UniversalClient
+}
+
+func NewNonceRepository(client redis.UniversalClient) *NonceRepository {
+	return &NonceRepository{client: client}
+}
+
+// Claim records the nonce for ttl and reports whether it was unused. Nonces are scoped
+// per business, so two businesses picking the same value don't collide.
+func (r *NonceRepository) Claim(ctx context.Context, businessID, nonce string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, nonceKey(businessID, nonce), "1", ttl).Result()
+}
+
+func nonceKey(businessID, nonce string) string {
+	return "auth_nonce:" + businessID + ":" + nonce
+}
@@ -0,0 +1,35 @@
+package nonce_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	nonceRepo "github.com/panbeh/otp-backend/internal/repository/nonceRepo"
+)
+
+func TestNonceRepository_Claim(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	repo := nonceRepo.NewNonceRepository(client)
+
+	if ok, err := repo.Claim(ctx, "b1", "n1", time.Minute); err != nil || !ok {
+		t.Fatalf("expected first claim to succeed, got ok=%v err=%v", ok, err)
+	}
+	if ok, err := repo.Claim(ctx, "b1", "n1", time.Minute); err != nil || ok {
+		t.Fatalf("expected replayed nonce to be rejected, got ok=%v err=%v", ok, err)
+	}
+	if ok, err := repo.Claim(ctx, "b2", "n1", time.Minute); err != nil || !ok {
+		t.Fatalf("expected same nonce from another business to succeed, got ok=%v err=%v", ok, err)
+	}
+
+	mr.FastForward(time.Minute)
+	if ok, err := repo.Claim(ctx, "b1", "n1", time.Minute); err != nil || !ok {
+		t.Fatalf("expected nonce to be claimable after ttl, got ok=%v err=%v", ok, err)
+	}
+}
@@ -2,6 +2,8 @@ package otp
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"time"
 
@@ -12,15 +14,51 @@ import (
 
 type OTPRepository struct {
 	client redis.UniversalClient
+	limits otp.SendLimits
+	hasher otp.CodeHasher
 }
 
-func NewOTPRepository(client redis.UniversalClient) otp.Repository {
-	return &OTPRepository{client: client}
+func NewOTPRepository(client redis.UniversalClient, limits otp.SendLimits, hasher otp.CodeHasher) otp.Repository {
+	return &OTPRepository{client: client, limits: limits, hasher: hasher}
 }
 
+// otpPayload is the value stored in Redis. Only the keyed hash of the code is kept.
+// SendID is the send's member in the hourly/daily sets, so Release can give it back.
 type otpPayload struct {
-	Code      string    `json:"code"`
-	ExpiresAt time.Time `json:"expires_at"`
+	CodeHash    string    `json:"code_hash"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	Attempts    int       `json:"attempts"`
+	MaxAttempts int       `json:"max_attempts"`
+	SendID      string    `json:"send_id,omitempty"`
+}
+
+// consumeRetries bounds how often Consume retries after losing a race for the OTP.
+const consumeRetries = 5
+
+func load(ctx context.Context, c redis.Cmdable, key string) (otpPayload, error) {
+	val, err := c.Get(ctx, key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return otpPayload{}, otp.ErrNotFound
+		}
+		return otpPayload{}, err
+	}
+	var p otpPayload
+	if err := json.Unmarshal(val, &p); err != nil {
+		return otpPayload{}, err
+	}
+	return p, nil
+}
+
+func (p otpPayload) otp(businessID string, phone otp.PhoneNumber) otp.OTP {
+	return otp.OTP{
+		BusinessID:  businessID,
+		PhoneNumber: phone,
+		CodeHash:    p.CodeHash,
+		ExpiresAt:   p.ExpiresAt,
+		Attempts:    p.Attempts,
+		MaxAttempts: otp.MaxAttempts(p.MaxAttempts),
+	}
 }
 
 func (r *OTPRepository) Save(ctx context.Context, o otp.OTP) error {
@@ -29,63 +67,251 @@ func (r *OTPRepository) Save(ctx context.Context, o otp.OTP) error {
 	if ttl <= 0 {
 		return nil
 	}
-	b, err := json.Marshal(otpPayload{Code: o.Code, ExpiresAt: o.ExpiresAt})
+	member, err := sendMember()
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(otpPayload{
+		CodeHash:    r.codeHash(o),
+		ExpiresAt:   o.ExpiresAt,
+		Attempts:    o.Attempts,
+		MaxAttempts: int(o.MaxAttempts),
+		SendID:      member,
+	})
 	if err != nil {
 		return err
 	}
-	return r.client.Set(ctx, key, b, ttl).Err()
-}
 
-func (r *OTPRepository) Get(ctx context.Context, businessID string, phone otp.IranPhoneNumber) (otp.OTP, error) {
-	key := otpKey(businessID, phone)
-	val, err := r.client.Get(ctx, key).Bytes()
+	// Limits are checked and the OTP is written in one script so concurrent sends
+	// can't both slip through the cooldown or the sliding windows.
+	// Sends are tracked as sorted-set members scored by send time (ms); entries
+	// older than the window are trimmed before counting.
+	// Returns {0, 0} when stored, otherwise {reason, retry_after_ms} where reason
+	// is 1 for cooldown, 2 for the hourly cap and 3 for the daily cap.
+	const script = `
+local otpKey, cooldownKey, hourKey, dayKey = KEYS[1], KEYS[2], KEYS[3], KEYS[4]
+local payload = ARGV[1]
+local ttl = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local member = ARGV[4]
+local cooldown = tonumber(ARGV[5])
+local perHour = tonumber(ARGV[6])
+local perDay = tonumber(ARGV[7])
+
+if cooldown > 0 then
+  local left = redis.call("PTTL", cooldownKey)
+  if left > 0 then
+    return {1, left}
+  end
+end
+
+local function wait(key, window, limit)
+  if limit <= 0 then
+    return 0
+  end
+  redis.call("ZREMRANGEBYSCORE", key, "-inf", now - window)
+  if redis.call("ZCARD", key) < limit then
+    return 0
+  end
+  local oldest = redis.call("ZRANGE", key, 0, 0, "WITHSCORES")
+  return math.max(tonumber(oldest[2]) + window - now, 1)
+end
+
+local left = wait(hourKey, 3600000, perHour)
+if left > 0 then
+  return {2, left}
+end
+left = wait(dayKey, 86400000, perDay)
+if left > 0 then
+  return {3, left}
+end
+
+if cooldown > 0 then
+  redis.call("SET", cooldownKey, "1", "PX", cooldown)
+end
+if perHour > 0 then
+  redis.call("ZADD", hourKey, now, member)
+  redis.call("PEXPIRE", hourKey, 3600000)
+end
+if perDay > 0 then
+  redis.call("ZADD", dayKey, now, member)
+  redis.call("PEXPIRE", dayKey, 86400000)
+end
+redis.call("SET", otpKey, payload, "PX", ttl)
+return {0, 0}
+`
+	keys := []string{
+		key,
+		cooldownKey(o.BusinessID, o.PhoneNumber),
+		sendsKey("hour", o.BusinessID, o.PhoneNumber),
+		sendsKey("day", o.BusinessID, o.PhoneNumber),
+	}
+	res, err := r.client.Eval(ctx, script, keys,
+		b,
+		ttl.Milliseconds(),
+		time.Now().UnixMilli(),
+		member,
+		r.limits.Cooldown.Milliseconds(),
+		r.limits.PerHour,
+		r.limits.PerDay,
+	).Int64Slice()
 	if err != nil {
-		if err == redis.Nil {
-			return otp.OTP{}, otp.ErrNotFound
-		}
-		return otp.OTP{}, err
+		return err
+	}
+	if len(res) != 2 || res[0] == 0 {
+		return nil
 	}
 
-	var p otpPayload
-	if err := json.Unmarshal(val, &p); err != nil {
-		return otp.OTP{}, err
+	retryAfter := time.Duration(res[1]) * time.Millisecond
+	switch res[0] {
+	case 1:
+		return &otp.RateLimitError{Reason: otp.RateLimitReasonCooldown, RetryAfter: retryAfter}
+	case 2:
+		return &otp.RateLimitError{Reason: otp.RateLimitReasonHourlyCap, RetryAfter: retryAfter}
+	default:
+		return &otp.RateLimitError{Reason: otp.RateLimitReasonDailyCap, RetryAfter: retryAfter}
 	}
+}
 
-	return otp.OTP{
-		BusinessID:  businessID,
-		PhoneNumber: phone,
-		Code:        p.Code,
-		ExpiresAt:   p.ExpiresAt,
-	}, nil
+func (r *OTPRepository) Get(ctx context.Context, businessID string, phone otp.PhoneNumber) (otp.OTP, error) {
+	p, err := load(ctx, r.client, otpKey(businessID, phone))
+	if err != nil {
+		return otp.OTP{}, err
+	}
+	return p.otp(businessID, phone), nil
 }
 
-func (r *OTPRepository) Delete(ctx context.Context, businessID string, phone otp.IranPhoneNumber) error {
+func (r *OTPRepository) Delete(ctx context.Context, businessID string, phone otp.PhoneNumber) error {
 	return r.client.Del(ctx, otpKey(businessID, phone)).Err()
 }
 
-func (r *OTPRepository) Consume(ctx context.Context, businessID string, phone otp.IranPhoneNumber, code string) (bool, error) {
-	// Atomic compare-and-delete to guarantee single-use OTP.
+func (r *OTPRepository) Release(ctx context.Context, o otp.OTP) error {
+	// The OTP is only released while it is still the one Save stored; a newer send
+	// keeps its code and its place in the limits.
 	const script = `
-local key = KEYS[1]
-local expected = ARGV[1]
-local val = redis.call("GET", key)
+local otpKey, cooldownKey, hourKey, dayKey = KEYS[1], KEYS[2], KEYS[3], KEYS[4]
+local val = redis.call("GET", otpKey)
 if not val then
   return 0
 end
 local decoded = cjson.decode(val)
-if decoded["code"] ~= expected then
+if decoded["code_hash"] ~= ARGV[1] then
   return 0
 end
-redis.call("DEL", key)
+redis.call("DEL", otpKey, cooldownKey)
+local member = decoded["send_id"]
+if type(member) == "string" and member ~= "" then
+  redis.call("ZREM", hourKey, member)
+  redis.call("ZREM", dayKey, member)
+end
 return 1
 `
-	res, err := r.client.Eval(ctx, script, []string{otpKey(businessID, phone)}, code).Int()
+	keys := []string{
+		otpKey(o.BusinessID, o.PhoneNumber),
+		cooldownKey(o.BusinessID, o.PhoneNumber),
+		sendsKey("hour", o.BusinessID, o.PhoneNumber),
+		sendsKey("day", o.BusinessID, o.PhoneNumber),
+	}
+	return r.client.Eval(ctx, script, keys, r.codeHash(o)).Err()
+}
+
+func (r *OTPRepository) Consume(ctx context.Context, businessID string, phone otp.PhoneNumber, check otp.CodeCheck) (bool, error) {
+	// The OTP is watched while check runs, so a concurrent Consume or Save makes the
+	// transaction fail and the whole read-check-write is retried: a code matches at most
+	// once and every mismatch is counted.
+	key := otpKey(businessID, phone)
+	var ok bool
+	consume := func(tx *redis.Tx) error {
+		payload, err := load(ctx, tx, key)
+		if err == otp.ErrNotFound {
+			ok, err = check(otp.OTP{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		stored := payload.otp(businessID, phone)
+		if ok, err = check(stored); err != nil {
+			return err
+		}
+		if ok {
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Del(ctx, key)
+				return nil
+			})
+			return err
+		}
+
+		// A mismatch bumps the attempt counter in place, keeping the TTL; once
+		// max_attempts failures are recorded the OTP stays locked until it expires.
+		payload.Attempts++
+		ttl, err := tx.PTTL(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		if ttl > 0 {
+			val, err := json.Marshal(payload)
+			if err != nil {
+				return err
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, key, val, ttl)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if payload.otp(businessID, phone).Locked() {
+			return otp.ErrTooManyAttempts
+		}
+		return nil
+	}
+
+	var err error
+	for i := 0; i < consumeRetries; i++ {
+		ok = false
+		if err = r.client.Watch(ctx, consume, key); err != redis.TxFailedErr {
+			break
+		}
+	}
 	if err != nil {
 		return false, err
 	}
-	return res == 1, nil
+	return ok, nil
+}
+
+// codeHash is the stored form of o's code; OTPs read back from Redis already carry it.
+func (r *OTPRepository) codeHash(o otp.OTP) string {
+	if o.CodeHash != "" {
+		return o.CodeHash
+	}
+	return r.hasher.Hash(o.BusinessID, o.PhoneNumber, o.Code)
+}
+
+// All keys of one business+phone share the {business:phone} hash tag, so the Save and
+// Release scripts, which touch all four, stay on a single Redis Cluster slot.
+func otpKey(businessID string, phone otp.PhoneNumber) string {
+	return "otp:" + slotTag(businessID, phone)
+}
+
+func cooldownKey(businessID string, phone otp.PhoneNumber) string {
+	return "otp_cooldown:" + slotTag(businessID, phone)
 }
 
-func otpKey(businessID string, phone otp.IranPhoneNumber) string {
-	return "otp:" + businessID + ":" + string(phone)
+func sendsKey(window, businessID string, phone otp.PhoneNumber) string {
+	return "otp_sends:" + window + ":" + slotTag(businessID, phone)
+}
+
+func slotTag(businessID string, phone otp.PhoneNumber) string {
+	return "{" + businessID + ":" + string(phone) + "}"
+}
+
+// sendMember returns a unique sorted-set member so sends in the same millisecond are all counted.
+func sendMember() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
 }
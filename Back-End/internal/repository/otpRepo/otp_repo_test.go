@@ -0,0 +1,382 @@
+package otp_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+	oTPRepo "github.com/panbeh/otp-backend/internal/repository/otpRepo"
+)
+
+func newTestRepo(t *testing.T, limits otp.SendLimits) (otp.Repository, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return oTPRepo.NewOTPRepository(client, limits, testHasher(t)), mr
+}
+
+func testHasher(t *testing.T) otp.CodeHasher {
+	t.Helper()
+	h, err := otp.NewCodeHasher([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("hasher: %v", err)
+	}
+	return h
+}
+
+// codeCheck verifies code the way the OTP service does, with the default policy.
+func codeCheck(t *testing.T, phone otp.PhoneNumber, code string) otp.CodeCheck {
+	svc := otp.NewService(otp.ServiceConfig{Hasher: testHasher(t)})
+	return func(stored otp.OTP) (bool, error) {
+		return svc.Verify(stored, "b1", phone, code, otp.Policy{})
+	}
+}
+
+func TestOTPRepository_Consume_SingleUse(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestRepo(t, otp.SendLimits{})
+
+	o := otp.OTP{
+		BusinessID:  "b1",
+		PhoneNumber: "+989123456789",
+		Code:        "123456",
+		ExpiresAt:   time.Now().Add(time.Minute),
+		MaxAttempts: 3,
+	}
+	if err := repo.Save(ctx, o); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	ok, err := repo.Consume(ctx, "b1", "+989123456789", codeCheck(t, "+989123456789", "123456"))
+	if err != nil || !ok {
+		t.Fatalf("expected ok, got ok=%v err=%v", ok, err)
+	}
+	ok, err = repo.Consume(ctx, "b1", "+989123456789", codeCheck(t, "+989123456789", "123456"))
+	if err != nil || ok {
+		t.Fatalf("expected second consume to fail, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestOTPRepository_Consume_ConcurrentMatchesOnce(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestRepo(t, otp.SendLimits{})
+
+	err := repo.Save(ctx, otp.OTP{
+		BusinessID:  "b1",
+		PhoneNumber: "+989123456789",
+		Code:        "123456",
+		ExpiresAt:   time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		matched int
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := repo.Consume(ctx, "b1", "+989123456789", codeCheck(t, "+989123456789", "123456"))
+			if err != nil && err != redis.TxFailedErr {
+				t.Errorf("consume: %v", err)
+			}
+			if ok {
+				mu.Lock()
+				matched++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if matched != 1 {
+		t.Fatalf("expected exactly one match, got %d", matched)
+	}
+}
+
+func TestOTPRepository_Consume_CheckErrorChangesNothing(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestRepo(t, otp.SendLimits{})
+
+	err := repo.Save(ctx, otp.OTP{
+		BusinessID:  "b1",
+		PhoneNumber: "+989123456789",
+		Code:        "123456",
+		ExpiresAt:   time.Now().Add(time.Minute),
+		MaxAttempts: 3,
+	})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	if _, err := repo.Consume(ctx, "b1", "+989123456789", codeCheck(t, "+989123456789", "12ab")); !errors.Is(err, otp.ErrInvalidCode) {
+		t.Fatalf("expected ErrInvalidCode, got %v", err)
+	}
+	stored, err := repo.Get(ctx, "b1", "+989123456789")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if stored.Attempts != 0 {
+		t.Fatalf("expected a malformed code not to count as an attempt, got %d", stored.Attempts)
+	}
+}
+
+func TestOTPRepository_Consume_LocksAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	repo, mr := newTestRepo(t, otp.SendLimits{})
+
+	o := otp.OTP{
+		BusinessID:  "b1",
+		PhoneNumber: "+989123456789",
+		Code:        "123456",
+		ExpiresAt:   time.Now().Add(time.Minute),
+		MaxAttempts: 3,
+	}
+	if err := repo.Save(ctx, o); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	for i := 1; i < 3; i++ {
+		ok, err := repo.Consume(ctx, "b1", "+989123456789", codeCheck(t, "+989123456789", "000000"))
+		if err != nil || ok {
+			t.Fatalf("attempt %d: expected mismatch, got ok=%v err=%v", i, ok, err)
+		}
+	}
+
+	stored, err := repo.Get(ctx, "b1", "+989123456789")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if stored.Attempts != 2 {
+		t.Fatalf("expected 2 recorded attempts, got %d", stored.Attempts)
+	}
+	if mr.TTL("otp:{b1:+989123456789}") <= 0 {
+		t.Fatalf("expected TTL to be preserved after failed attempt")
+	}
+
+	if _, err := repo.Consume(ctx, "b1", "+989123456789", codeCheck(t, "+989123456789", "000000")); err != otp.ErrTooManyAttempts {
+		t.Fatalf("expected ErrTooManyAttempts on last failure, got %v", err)
+	}
+
+	// Even the correct code is rejected once the OTP is locked.
+	ok, err := repo.Consume(ctx, "b1", "+989123456789", codeCheck(t, "+989123456789", "123456"))
+	if err != otp.ErrTooManyAttempts || ok {
+		t.Fatalf("expected locked OTP, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestOTPRepository_Save_EnforcesCooldown(t *testing.T) {
+	ctx := context.Background()
+	repo, mr := newTestRepo(t, otp.SendLimits{Cooldown: time.Minute})
+
+	o := otp.OTP{
+		BusinessID:  "b1",
+		PhoneNumber: "+989123456789",
+		Code:        "123456",
+		ExpiresAt:   time.Now().Add(5 * time.Minute),
+	}
+	if err := repo.Save(ctx, o); err != nil {
+		t.Fatalf("first save: %v", err)
+	}
+
+	o.Code = "654321"
+	err := repo.Save(ctx, o)
+	var rlErr *otp.RateLimitError
+	if !errors.As(err, &rlErr) || rlErr.Reason != otp.RateLimitReasonCooldown {
+		t.Fatalf("expected cooldown RateLimitError, got %v", err)
+	}
+	if rlErr.RetryAfter <= 0 || rlErr.RetryAfter > time.Minute {
+		t.Fatalf("unexpected RetryAfter: %v", rlErr.RetryAfter)
+	}
+	if !errors.Is(err, otp.ErrRateLimited) {
+		t.Fatalf("expected error to match ErrRateLimited")
+	}
+
+	// The rejected send must not replace the pending code.
+	ok, err := repo.Consume(ctx, "b1", "+989123456789", codeCheck(t, "+989123456789", "123456"))
+	if err != nil || !ok {
+		t.Fatalf("expected original code to be kept, got ok=%v err=%v", ok, err)
+	}
+
+	// Cooldown is per business.
+	other := o
+	other.BusinessID = "b2"
+	if err := repo.Save(ctx, other); err != nil {
+		t.Fatalf("expected other business to be allowed, got %v", err)
+	}
+
+	mr.FastForward(time.Minute)
+	if err := repo.Save(ctx, o); err != nil {
+		t.Fatalf("expected save after cooldown, got %v", err)
+	}
+}
+
+func TestOTPRepository_Save_EnforcesHourlyCap(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestRepo(t, otp.SendLimits{PerHour: 2, PerDay: 10})
+
+	o := otp.OTP{
+		BusinessID:  "b1",
+		PhoneNumber: "+989123456789",
+		Code:        "123456",
+		ExpiresAt:   time.Now().Add(5 * time.Minute),
+	}
+	for i := 0; i < 2; i++ {
+		if err := repo.Save(ctx, o); err != nil {
+			t.Fatalf("save %d: %v", i, err)
+		}
+	}
+
+	err := repo.Save(ctx, o)
+	var rlErr *otp.RateLimitError
+	if !errors.As(err, &rlErr) || rlErr.Reason != otp.RateLimitReasonHourlyCap {
+		t.Fatalf("expected hourly RateLimitError, got %v", err)
+	}
+	if rlErr.RetryAfter <= 59*time.Minute || rlErr.RetryAfter > time.Hour {
+		t.Fatalf("expected RetryAfter close to an hour, got %v", rlErr.RetryAfter)
+	}
+}
+
+func TestOTPRepository_Release_GivesBackTheSend(t *testing.T) {
+	ctx := context.Background()
+	repo, _ := newTestRepo(t, otp.SendLimits{Cooldown: time.Minute, PerHour: 1, PerDay: 1})
+
+	o := otp.OTP{
+		BusinessID:  "b1",
+		PhoneNumber: "+989123456789",
+		Code:        "123456",
+		ExpiresAt:   time.Now().Add(5 * time.Minute),
+	}
+	if err := repo.Save(ctx, o); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if err := repo.Release(ctx, o); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if _, err := repo.Get(ctx, "b1", "+989123456789"); !errors.Is(err, otp.ErrNotFound) {
+		t.Fatalf("expected the released code deleted, got %v", err)
+	}
+
+	// Neither the cooldown nor the caps remember the failed send.
+	retry := o
+	retry.Code = "654321"
+	if err := repo.Save(ctx, retry); err != nil {
+		t.Fatalf("expected the retry to be allowed, got %v", err)
+	}
+
+	// Releasing a code that has since been replaced leaves the new one alone.
+	if err := repo.Release(ctx, o); err != nil {
+		t.Fatalf("release stale: %v", err)
+	}
+	ok, err := repo.Consume(ctx, "b1", "+989123456789", codeCheck(t, "+989123456789", "654321"))
+	if err != nil || !ok {
+		t.Fatalf("expected the newer code to be kept, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestOTPRepository_Save_StoresOnlyCodeHash(t *testing.T) {
+	ctx := context.Background()
+	repo, mr := newTestRepo(t, otp.SendLimits{})
+
+	o := otp.OTP{
+		BusinessID:  "b1",
+		PhoneNumber: "+989123456789",
+		Code:        "123456",
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}
+	if err := repo.Save(ctx, o); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	raw, err := mr.Get("otp:{b1:+989123456789}")
+	if err != nil {
+		t.Fatalf("raw get: %v", err)
+	}
+	if strings.Contains(raw, "123456") {
+		t.Fatalf("plaintext code stored at rest: %s", raw)
+	}
+
+	stored, err := repo.Get(ctx, "b1", "+989123456789")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if stored.Code != "" || stored.CodeHash != testHasher(t).Hash("b1", "+989123456789", "123456") {
+		t.Fatalf("unexpected stored OTP: %#v", stored)
+	}
+}
+
+func TestOTPRepository_SendAndConsume_AcrossPhoneFormats(t *testing.T) {
+	ctx := context.Background()
+	formats := []string{"09123456789", "9123456789", "+989123456789"}
+
+	for _, sendForm := range formats {
+		for _, verifyForm := range formats {
+			repo, _ := newTestRepo(t, otp.SendLimits{})
+
+			sendPhone, err := otp.NewIranPhoneNumber(sendForm)
+			if err != nil {
+				t.Fatalf("parse %q: %v", sendForm, err)
+			}
+			err = repo.Save(ctx, otp.OTP{
+				BusinessID:  "b1",
+				PhoneNumber: sendPhone.PhoneNumber(),
+				Code:        "123456",
+				ExpiresAt:   time.Now().Add(time.Minute),
+			})
+			if err != nil {
+				t.Fatalf("save: %v", err)
+			}
+
+			verifyPhone, _ := otp.NewIranPhoneNumber(verifyForm)
+			ok, err := repo.Consume(ctx, "b1", verifyPhone.PhoneNumber(), codeCheck(t, verifyPhone.PhoneNumber(), "123456"))
+			if err != nil || !ok {
+				t.Fatalf("sent to %q, consumed as %q: ok=%v err=%v", sendForm, verifyForm, ok, err)
+			}
+		}
+	}
+}
+
+func TestOTPRepository_KeysShareOneClusterSlot(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	// Run through a cluster client so the repository is exercised the way it is in
+	// cluster mode; miniredis serves every slot from one node.
+	client := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{mr.Addr()}})
+	t.Cleanup(func() { _ = client.Close() })
+	repo := oTPRepo.NewOTPRepository(client, otp.SendLimits{Cooldown: time.Minute, PerHour: 5, PerDay: 10}, testHasher(t))
+
+	err := repo.Save(ctx, otp.OTP{
+		BusinessID:  "b1",
+		PhoneNumber: "+989123456789",
+		Code:        "123456",
+		ExpiresAt:   time.Now().Add(time.Minute),
+	})
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if ok, err := repo.Consume(ctx, "b1", "+989123456789", codeCheck(t, "+989123456789", "123456")); err != nil || !ok {
+		t.Fatalf("consume: ok=%v err=%v", ok, err)
+	}
+
+	keys := mr.Keys()
+	if len(keys) < 3 {
+		t.Fatalf("expected cooldown and send counters to remain, got %v", keys)
+	}
+	slot := client.ClusterKeySlot(ctx, keys[0]).Val()
+	for _, k := range keys[1:] {
+		if s := client.ClusterKeySlot(ctx, k).Val(); s != slot {
+			t.Fatalf("key %q is on slot %d, %q on %d", keys[0], slot, k, s)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package usage
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/panbeh/otp-backend/internal/domain/usage"
+)
+
+const (
+	dirtyKey  = "usage:dirty"
+	dayLayout = "2006-01-02"
+	// Day counters outlive the month they belong to so a late flush still finds them.
+	dayTTL   = 40 * 24 * time.Hour
+	monthTTL = 62 * 24 * time.Hour
+)
+
+// UsageCounter keeps per-business daily counters in Redis. Every increment also marks
+// the business-day dirty so the flusher knows which rows to copy to Postgres.
+type UsageCounter struct {
+	client redis.UniversalClient
+}
+
+func NewUsageCounter(client redis.UniversalClient) usage.Counter {
+	return &UsageCounter{client: client}
+}
+
+func (r *UsageCounter) Incr(ctx context.Context, businessID string, event usage.Event, at time.Time) error {
+	day := usage.Day(at)
+	_, err := r.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HIncrBy(ctx, dayKey(businessID, day), string(event), 1)
+		p.Expire(ctx, dayKey(businessID, day), dayTTL)
+		if event == usage.EventSend {
+			p.Incr(ctx, monthKey(businessID, at))
+			p.Expire(ctx, monthKey(businessID, at), monthTTL)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// The dirty set lives on another cluster slot, so it can't join the transaction.
+	// Marking after the increment means a flush never misses it.
+	return r.client.SAdd(ctx, dirtyKey, dirtyMember(businessID, day)).Err()
+}
+
+func (r *UsageCounter) MonthSends(ctx context.Context, businessID string, at time.Time) (int64, error) {
+	n, err := r.client.Get(ctx, monthKey(businessID, at)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return n, err
+}
+
+func (r *UsageCounter) PopDirty(ctx context.Context, n int) ([]usage.Daily, error) {
+	members, err := r.client.SPopN(ctx, dirtyKey, int64(n)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []usage.Daily
+	for _, m := range members {
+		businessID, dayStr, ok := strings.Cut(m, "|")
+		if !ok {
+			continue
+		}
+		day, err := time.Parse(dayLayout, dayStr)
+		if err != nil {
+			continue
+		}
+		fields, err := r.client.HGetAll(ctx, dayKey(businessID, day)).Result()
+		if err != nil {
+			// Put back what we popped but couldn't read, so nothing is lost.
+			_ = r.client.SAdd(ctx, dirtyKey, m).Err()
+			return out, err
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		out = append(out, usage.Daily{
+			BusinessID:      businessID,
+			Day:             day,
+			Sends:           parseCount(fields[string(usage.EventSend)]),
+			VerifySucceeded: parseCount(fields[string(usage.EventVerifySucceeded)]),
+			VerifyFailed:    parseCount(fields[string(usage.EventVerifyFailed)]),
+		})
+	}
+	return out, nil
+}
+
+func (r *UsageCounter) MarkDirty(ctx context.Context, days []usage.Daily) error {
+	if len(days) == 0 {
+		return nil
+	}
+	members := make([]any, 0, len(days))
+	for _, d := range days {
+		members = append(members, dirtyMember(d.BusinessID, d.Day))
+	}
+	return r.client.SAdd(ctx, dirtyKey, members...).Err()
+}
+
+// A business's counters share the {business} hash tag so Incr's transaction stays on
+// one cluster slot.
+func dayKey(businessID string, day time.Time) string {
+	return "usage:{" + businessID + "}:" + day.Format(dayLayout)
+}
+
+func monthKey(businessID string, at time.Time) string {
+	return "usage_month:{" + businessID + "}:" + usage.MonthStart(at).Format("2006-01")
+}
+
+func dirtyMember(businessID string, day time.Time) string {
+	return businessID + "|" + day.Format(dayLayout)
+}
+
+func parseCount(v string) int64 {
+	n, _ := strconv.ParseInt(v, 10, 64)
+	return n
+}
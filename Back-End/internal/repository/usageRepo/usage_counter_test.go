@@ -0,0 +1,123 @@
+package usage_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/panbeh/otp-backend/internal/domain/usage"
+	usageRepo "github.com/panbeh/otp-backend/internal/repository/usageRepo"
+)
+
+func newTestCounter(t *testing.T) usage.Counter {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return usageRepo.NewUsageCounter(client)
+}
+
+func TestUsageCounter_CountsPerBusinessPerDay(t *testing.T) {
+	ctx := context.Background()
+	counter := newTestCounter(t)
+
+	day1 := time.Date(2026, 3, 31, 10, 0, 0, 0, time.UTC)
+	day2 := time.Date(2026, 4, 1, 10, 0, 0, 0, time.UTC)
+	events := []struct {
+		biz   string
+		event usage.Event
+		at    time.Time
+	}{
+		{"b1", usage.EventSend, day1},
+		{"b1", usage.EventSend, day1},
+		{"b1", usage.EventVerifySucceeded, day1},
+		{"b1", usage.EventVerifyFailed, day1},
+		{"b1", usage.EventSend, day2},
+		{"b2", usage.EventSend, day1},
+	}
+	for _, ev := range events {
+		if err := counter.Incr(ctx, ev.biz, ev.event, ev.at); err != nil {
+			t.Fatalf("incr: %v", err)
+		}
+	}
+
+	// Monthly sends reset with the calendar month.
+	if n, _ := counter.MonthSends(ctx, "b1", day1); n != 2 {
+		t.Fatalf("expected 2 sends in March, got %d", n)
+	}
+	if n, _ := counter.MonthSends(ctx, "b1", day2); n != 1 {
+		t.Fatalf("expected 1 send in April, got %d", n)
+	}
+
+	days, err := counter.PopDirty(ctx, 10)
+	if err != nil {
+		t.Fatalf("pop: %v", err)
+	}
+	sort.Slice(days, func(i, j int) bool {
+		if days[i].BusinessID != days[j].BusinessID {
+			return days[i].BusinessID < days[j].BusinessID
+		}
+		return days[i].Day.Before(days[j].Day)
+	})
+	want := []usage.Daily{
+		{BusinessID: "b1", Day: usage.Day(day1), Sends: 2, VerifySucceeded: 1, VerifyFailed: 1},
+		{BusinessID: "b1", Day: usage.Day(day2), Sends: 1},
+		{BusinessID: "b2", Day: usage.Day(day1), Sends: 1},
+	}
+	if len(days) != len(want) {
+		t.Fatalf("expected %d dirty days, got %#v", len(want), days)
+	}
+	for i := range want {
+		if days[i] != want[i] {
+			t.Fatalf("day %d: expected %#v, got %#v", i, want[i], days[i])
+		}
+	}
+
+	if days, _ := counter.PopDirty(ctx, 10); len(days) != 0 {
+		t.Fatalf("expected nothing dirty after pop, got %#v", days)
+	}
+}
+
+func TestUsageCounter_PopReturnsTotalsAndMarkDirtyRequeues(t *testing.T) {
+	ctx := context.Background()
+	counter := newTestCounter(t)
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	_ = counter.Incr(ctx, "b1", usage.EventSend, at)
+	first, _ := counter.PopDirty(ctx, 10)
+	_ = counter.Incr(ctx, "b1", usage.EventSend, at)
+
+	// A later pop carries the day's running total, not the delta since the last pop.
+	second, _ := counter.PopDirty(ctx, 10)
+	if len(first) != 1 || len(second) != 1 || second[0].Sends != 2 {
+		t.Fatalf("expected absolute totals, got %#v then %#v", first, second)
+	}
+
+	if err := counter.MarkDirty(ctx, second); err != nil {
+		t.Fatalf("mark dirty: %v", err)
+	}
+	if again, _ := counter.PopDirty(ctx, 10); len(again) != 1 || again[0] != second[0] {
+		t.Fatalf("expected requeued day, got %#v", again)
+	}
+}
+
+func TestUsageCounter_WorksOnCluster(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	// The cluster client refuses transactions spanning slots, which is what this guards.
+	client := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{mr.Addr()}})
+	t.Cleanup(func() { _ = client.Close() })
+	counter := usageRepo.NewUsageCounter(client)
+
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	if err := counter.Incr(ctx, "b1", usage.EventSend, at); err != nil {
+		t.Fatalf("incr: %v", err)
+	}
+	if days, err := counter.PopDirty(ctx, 10); err != nil || len(days) != 1 || days[0].Sends != 1 {
+		t.Fatalf("expected one dirty day, got %#v (err %v)", days, err)
+	}
+}
@@ -0,0 +1,57 @@
+package usage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/usage"
+)
+
+type EventStore struct {
+	db *sql.DB
+}
+
+func NewEventStore(db *sql.DB) usage.EventStore {
+	return &EventStore{db: db}
+}
+
+func (r *EventStore) Record(ctx context.Context, businessID string, event usage.Event, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO usage_events (business_id, event, occurred_at)
+		VALUES ($1, $2, $3)
+	`, businessID, string(event), at)
+	return err
+}
+
+func (r *EventStore) Summarize(ctx context.Context, q usage.ReportQuery) ([]usage.Summary, error) {
+	// Buckets are cut in UTC, matching usage.Day and usage.MonthStart. The range
+	// predicate stays on the raw column so the (business_id, occurred_at) index is used.
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT date_trunc($4, occurred_at AT TIME ZONE 'UTC') AS period,
+		       count(*) FILTER (WHERE event = 'sends'),
+		       count(*) FILTER (WHERE event = 'verify_succeeded'),
+		       count(*) FILTER (WHERE event = 'verify_failed'),
+		       count(*) FILTER (WHERE event = 'expired')
+		FROM usage_events
+		WHERE business_id = $1 AND occurred_at >= $2 AND occurred_at < $3
+		GROUP BY period
+		ORDER BY period
+	`, q.BusinessID, q.From, q.To, string(q.Granularity))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []usage.Summary
+	for rows.Next() {
+		var s usage.Summary
+		if err := rows.Scan(&s.Period, &s.Sends, &s.VerifySucceeded, &s.VerifyFailed, &s.Expired); err != nil {
+			return nil, err
+		}
+		// date_trunc on a timestamp without time zone comes back without a location.
+		s.Period = time.Date(s.Period.Year(), s.Period.Month(), s.Period.Day(), 0, 0, 0, 0, time.UTC)
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
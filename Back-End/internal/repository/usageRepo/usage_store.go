@@ -0,0 +1,71 @@
+package usage
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/panbeh/otp-backend/internal/domain/usage"
+)
+
+type UsageStore struct {
+	db *sql.DB
+}
+
+func NewUsageStore(db *sql.DB) usage.Store {
+	return &UsageStore{db: db}
+}
+
+func (r *UsageStore) UpsertDaily(ctx context.Context, days []usage.Daily) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, d := range days {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO usage_daily (business_id, day, sends, verify_succeeded, verify_failed, updated_at)
+			VALUES ($1, $2, $3, $4, $5, now())
+			ON CONFLICT (business_id, day) DO UPDATE
+			SET sends = EXCLUDED.sends,
+			    verify_succeeded = EXCLUDED.verify_succeeded,
+			    verify_failed = EXCLUDED.verify_failed,
+			    updated_at = now()
+		`, d.BusinessID, d.Day, d.Sends, d.VerifySucceeded, d.VerifyFailed)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *UsageStore) GetPlan(ctx context.Context, id string) (usage.Plan, error) {
+	var p usage.Plan
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, name, monthly_send_quota
+		FROM plans
+		WHERE id = $1
+	`, id).Scan(&p.ID, &p.Name, &p.MonthlySendQuota)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return usage.Plan{}, usage.ErrPlanNotFound
+		}
+		return usage.Plan{}, err
+	}
+	return p, nil
+}
+
+func (r *UsageStore) MonthlySendQuota(ctx context.Context, businessID string) (int64, error) {
+	// Businesses without a plan are unmetered.
+	var quota sql.NullInt64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT p.monthly_send_quota
+		FROM businesses b
+		LEFT JOIN plans p ON p.id = b.plan_id
+		WHERE b.id = $1
+	`, businessID).Scan(&quota)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	return quota.Int64, nil
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/apikey"
+	"github.com/panbeh/otp-backend/internal/domain/business"
+)
+
+// lastUsedResolution bounds how often a key's last_used_at is written, so a busy key
+// doesn't cost an UPDATE per request.
+const lastUsedResolution = time.Minute
+
+// APIKeyService manages business API keys and resolves presented keys for auth.
+type APIKeyService struct {
+	keys       apikey.Repository
+	businesses business.Repository
+	svc        *apikey.Service
+	now        func() time.Time
+}
+
+func NewAPIKeyService(keys apikey.Repository, businesses business.Repository, svc *apikey.Service) *APIKeyService {
+	return &APIKeyService{keys: keys, businesses: businesses, svc: svc, now: time.Now}
+}
+
+// Resolve authenticates key and checks it grants scope. Keys from the api_keys table
+// are tried first; the business's own token still works as a key with every scope.
+// Keys of a suspended business fail with business.ErrSuspended.
+func (s *APIKeyService) Resolve(ctx context.Context, key string, scope apikey.Scope) (apikey.APIKey, error) {
+	if strings.TrimSpace(key) == "" {
+		return apikey.APIKey{}, apikey.ErrInvalidKey
+	}
+
+	var b business.Business
+	k, err := s.keys.GetByKey(ctx, key)
+	switch {
+	case errors.Is(err, apikey.ErrNotFound):
+		k, b, err = s.legacyKey(ctx, key)
+	case err == nil:
+		b, err = s.businesses.GetByID(ctx, k.BusinessID)
+		if errors.Is(err, business.ErrNotFound) {
+			err = apikey.ErrNotFound
+		}
+	}
+	if err != nil {
+		return apikey.APIKey{}, err
+	}
+	if err := b.CheckActive(); err != nil {
+		if errors.Is(err, business.ErrNotFound) {
+			return apikey.APIKey{}, apikey.ErrNotFound
+		}
+		return apikey.APIKey{}, err
+	}
+	if err := s.svc.Authorize(k, scope); err != nil {
+		return apikey.APIKey{}, err
+	}
+
+	if now := s.now(); k.ID != "" && (k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= lastUsedResolution) {
+		// Last-used bookkeeping must not fail an otherwise valid request.
+		_ = s.keys.TouchLastUsed(ctx, k.ID, now)
+		k.LastUsedAt = &now
+	}
+	return k, nil
+}
+
+// legacyKey maps a business token onto a key with an empty ID.
+func (s *APIKeyService) legacyKey(ctx context.Context, token string) (apikey.APIKey, business.Business, error) {
+	b, err := s.businesses.GetByToken(ctx, token)
+	if errors.Is(err, business.ErrNotFound) {
+		return apikey.APIKey{}, business.Business{}, apikey.ErrNotFound
+	}
+	if err != nil {
+		return apikey.APIKey{}, business.Business{}, err
+	}
+	return apikey.APIKey{
+		BusinessID: b.ID,
+		Name:       "business token",
+		KeyHash:    b.TokenHash,
+		KeyPrefix:  b.TokenPrefix,
+		Scopes:     apikey.AllScopes,
+		CreatedAt:  b.CreatedAt,
+	}, b, nil
+}
+
+// Create issues a key for the business of issuer, the key making the request. Scopes
+// issuer doesn't hold are refused with apikey.ErrScopeDenied. The returned Key is the
+// only time the raw secret is available.
+func (s *APIKeyService) Create(ctx context.Context, issuer apikey.APIKey, name string, scopes []string) (apikey.APIKey, error) {
+	k, err := s.svc.NewKeyFor(issuer, name, scopes)
+	if err != nil {
+		return apikey.APIKey{}, err
+	}
+	if err := s.keys.Create(ctx, k); err != nil {
+		return apikey.APIKey{}, err
+	}
+	return k, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context, businessID string) ([]apikey.APIKey, error) {
+	return s.keys.ListByBusiness(ctx, businessID)
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, businessID, id string) error {
+	return s.keys.Revoke(ctx, businessID, id, s.now())
+}
@@ -0,0 +1,72 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/apikey"
+	"github.com/panbeh/otp-backend/internal/domain/business"
+	"github.com/panbeh/otp-backend/internal/service"
+)
+
+// fakeKeyRepo keeps keys by raw secret; other methods are unused.
+type fakeKeyRepo struct {
+	apikey.Repository
+	keys    map[string]apikey.APIKey
+	touched []string
+}
+
+func (f *fakeKeyRepo) GetByKey(ctx context.Context, key string) (apikey.APIKey, error) {
+	k, ok := f.keys[key]
+	if !ok {
+		return apikey.APIKey{}, apikey.ErrNotFound
+	}
+	return k, nil
+}
+
+func (f *fakeKeyRepo) TouchLastUsed(ctx context.Context, id string, at time.Time) error {
+	f.touched = append(f.touched, id)
+	return nil
+}
+
+func TestAPIKeyService_Resolve(t *testing.T) {
+	ctx := context.Background()
+	keys := &fakeKeyRepo{keys: map[string]apikey.APIKey{
+		"web":  {ID: "k1", BusinessID: "b1", Scopes: []apikey.Scope{apikey.ScopeOTPSend}},
+		"gone": {ID: "k2", BusinessID: "b2", Scopes: []apikey.Scope{apikey.ScopeOTPSend}},
+	}}
+	businesses := &fakeBusinesses{businesses: map[string]business.Business{
+		"b1": {ID: "b1", Status: business.StatusActive},
+		"b2": {ID: "b2", Status: business.StatusSuspended},
+	}}
+	svc := service.NewAPIKeyService(keys, businesses, apikey.NewService(apikey.ServiceConfig{}))
+
+	k, err := svc.Resolve(ctx, "web", apikey.ScopeOTPSend)
+	if err != nil || k.ID != "k1" {
+		t.Fatalf("expected key k1, got %#v err=%v", k, err)
+	}
+	if len(keys.touched) != 1 || keys.touched[0] != "k1" {
+		t.Fatalf("expected last-used to be recorded, got %v", keys.touched)
+	}
+
+	if _, err := svc.Resolve(ctx, "web", apikey.ScopeOTPVerify); !errors.Is(err, apikey.ErrScopeDenied) {
+		t.Fatalf("expected ErrScopeDenied, got %v", err)
+	}
+	if _, err := svc.Resolve(ctx, "gone", apikey.ScopeOTPSend); !errors.Is(err, business.ErrSuspended) {
+		t.Fatalf("expected ErrSuspended, got %v", err)
+	}
+}
+
+func TestAPIKeyService_Resolve_SuspendedBusinessToken(t *testing.T) {
+	token := "0123456789abcdef0123456789abcdef"
+	businesses := &fakeBusinesses{businesses: map[string]business.Business{
+		"b1": {ID: "b1", Status: business.StatusSuspended, TokenHash: business.HashToken(token)},
+	}}
+	svc := service.NewAPIKeyService(&fakeKeyRepo{}, businesses, apikey.NewService(apikey.ServiceConfig{}))
+
+	if _, err := svc.Resolve(context.Background(), token, apikey.ScopeOTPSend); !errors.Is(err, business.ErrSuspended) {
+		t.Fatalf("expected the legacy token of a suspended business to fail with ErrSuspended, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/panbeh/otp-backend/internal/domain/business"
+	"github.com/panbeh/otp-backend/internal/domain/usage"
+)
+
+// BusinessAdminService backs the operator-only business management routes.
+type BusinessAdminService struct {
+	repo  business.Repository
+	svc   *business.Service
+	plans usage.Store
+}
+
+func NewBusinessAdminService(repo business.Repository, svc *business.Service, plans usage.Store) *BusinessAdminService {
+	return &BusinessAdminService{repo: repo, svc: svc, plans: plans}
+}
+
+// Create registers a business. The returned Token is the only time the raw token is
+// available.
+func (s *BusinessAdminService) Create(ctx context.Context, name string) (business.Business, error) {
+	b, err := s.svc.NewBusiness(name)
+	if err != nil {
+		return business.Business{}, err
+	}
+	return s.repo.Create(ctx, b)
+}
+
+func (s *BusinessAdminService) List(ctx context.Context, filter business.ListFilter) ([]business.Business, error) {
+	return s.repo.List(ctx, filter)
+}
+
+func (s *BusinessAdminService) Get(ctx context.Context, id string) (business.Business, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *BusinessAdminService) Rename(ctx context.Context, id, name string) (business.Business, error) {
+	b, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return business.Business{}, err
+	}
+	b, err = s.svc.Rename(b, name)
+	if err != nil {
+		return business.Business{}, err
+	}
+	if err := s.repo.UpdateName(ctx, b.ID, b.Name); err != nil {
+		return business.Business{}, err
+	}
+	return b, nil
+}
+
+// SetPlan attaches an existing plan to the business; an empty planID detaches it and
+// leaves the business unmetered.
+func (s *BusinessAdminService) SetPlan(ctx context.Context, id, planID string) (business.Business, error) {
+	b, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return business.Business{}, err
+	}
+	if err := b.CheckActive(); errors.Is(err, business.ErrNotFound) {
+		return business.Business{}, err
+	}
+	if planID != "" {
+		if _, err := s.plans.GetPlan(ctx, planID); err != nil {
+			return business.Business{}, err
+		}
+	}
+	if err := s.repo.UpdatePlan(ctx, b.ID, planID); err != nil {
+		return business.Business{}, err
+	}
+	b.PlanID = planID
+	return b, nil
+}
+
+func (s *BusinessAdminService) Suspend(ctx context.Context, id string) (business.Business, error) {
+	return s.transition(ctx, id, s.svc.Suspend)
+}
+
+func (s *BusinessAdminService) Reactivate(ctx context.Context, id string) (business.Business, error) {
+	return s.transition(ctx, id, s.svc.Reactivate)
+}
+
+func (s *BusinessAdminService) Delete(ctx context.Context, id string) (business.Business, error) {
+	return s.transition(ctx, id, s.svc.Delete)
+}
+
+func (s *BusinessAdminService) transition(ctx context.Context, id string, apply func(business.Business) (business.Business, error)) (business.Business, error) {
+	b, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return business.Business{}, err
+	}
+	b, err = apply(b)
+	if err != nil {
+		return business.Business{}, err
+	}
+	if err := s.repo.UpdateStatus(ctx, b); err != nil {
+		return business.Business{}, err
+	}
+	return b, nil
+}
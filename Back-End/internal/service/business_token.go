@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/business"
+)
+
+// BusinessTokenService rotates business API tokens.
+type BusinessTokenService struct {
+	repo  business.Repository
+	svc   *business.Service
+	grace time.Duration
+}
+
+func NewBusinessTokenService(repo business.Repository, svc *business.Service, grace time.Duration) *BusinessTokenService {
+	return &BusinessTokenService{repo: repo, svc: svc, grace: grace}
+}
+
+// Rotate replaces the token of the business that owns token and returns the business
+// with its new raw token, which is not retrievable again. Only the current token may
+// rotate; a token that is merely in its grace window gets business.ErrInvalidToken.
+func (s *BusinessTokenService) Rotate(ctx context.Context, token string) (business.Business, error) {
+	if err := business.ValidateToken(token); err != nil {
+		return business.Business{}, err
+	}
+	b, err := s.repo.GetByToken(ctx, token)
+	if err != nil {
+		return business.Business{}, err
+	}
+	if err := b.CheckActive(); err != nil {
+		return business.Business{}, err
+	}
+	currentHash := business.HashToken(token)
+	if b.TokenHash != currentHash {
+		return business.Business{}, business.ErrInvalidToken
+	}
+
+	rotated, err := s.svc.RotateToken(b, s.grace)
+	if err != nil {
+		return business.Business{}, err
+	}
+	if err := s.repo.RotateToken(ctx, rotated, currentHash); err != nil {
+		return business.Business{}, err
+	}
+	return rotated, nil
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/business"
+	"github.com/panbeh/otp-backend/internal/domain/delivery"
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+	"github.com/panbeh/otp-backend/internal/sms"
+)
+
+// SentOTP is what the caller learns about a code it sent; the code itself only goes
+// to the phone. RequestID looks up the delivery status of the send.
+type SentOTP struct {
+	RequestID string
+	ExpiresAt time.Time
+}
+
+// OTPAppService sends and verifies codes on behalf of an authenticated business. The
+// business is re-read on every call, so changes to its OTP settings (allowed countries,
+// code format, TTL) apply to the next send, and one suspended after its key was resolved
+// is still refused with business.ErrSuspended.
+type OTPAppService struct {
+	businesses business.Repository
+	repo       otp.Repository
+	svc        *otp.Service
+	sender     sms.Sender
+	deliveries delivery.Repository
+	logger     *slog.Logger
+}
+
+func NewOTPAppService(businesses business.Repository, repo otp.Repository, svc *otp.Service, sender sms.Sender, deliveries delivery.Repository, logger *slog.Logger) *OTPAppService {
+	return &OTPAppService{businesses: businesses, repo: repo, svc: svc, sender: sender, deliveries: deliveries, logger: logger}
+}
+
+// Send issues a code for phone and hands it to the SMS sender. A code the gateway
+// refused is released again, so a retry isn't checked against a code nobody received
+// and the failed attempt doesn't count against the resend cooldown or send caps.
+func (s *OTPAppService) Send(ctx context.Context, businessID, phone string) (SentOTP, error) {
+	policy, err := s.policy(ctx, businessID)
+	if err != nil {
+		return SentOTP{}, err
+	}
+	p, err := otp.NewPhoneNumber(phone)
+	if err != nil {
+		return SentOTP{}, err
+	}
+	o, err := s.svc.NewOTP(businessID, p, policy)
+	if err != nil {
+		return SentOTP{}, err
+	}
+	if err := s.repo.Save(ctx, o); err != nil {
+		return SentOTP{}, err
+	}
+
+	// The ID is generated here, not taken from the caller, so it can't collide with
+	// another business's request.
+	requestID, err := newRequestID()
+	if err != nil {
+		return SentOTP{}, err
+	}
+	sendCtx := sms.WithRequest(ctx, sms.Request{ID: requestID, BusinessID: businessID})
+	if err := s.sender.Send(sendCtx, p, o.Code); err != nil {
+		if relErr := s.repo.Release(context.WithoutCancel(ctx), o); relErr != nil {
+			s.logger.WarnContext(ctx, "otp_release_failed", slog.Any("err", relErr))
+		}
+		return SentOTP{}, err
+	}
+	return SentOTP{RequestID: requestID, ExpiresAt: o.ExpiresAt}, nil
+}
+
+// Request returns the delivery status of one of the business's sends; other
+// businesses' requests are reported as delivery.ErrNotFound.
+func (s *OTPAppService) Request(ctx context.Context, businessID, id string) (delivery.Delivery, error) {
+	return s.deliveries.Get(ctx, businessID, id)
+}
+
+// Verify checks code against the pending OTP for phone and consumes it on a match.
+// Every wrong code counts against the OTP; once it is locked Verify fails with
+// otp.ErrTooManyAttempts until a new code is sent. Codes sent before a business was
+// suspended are kept but can't be used until it is reactivated.
+func (s *OTPAppService) Verify(ctx context.Context, businessID, phone, code string) (bool, error) {
+	policy, err := s.policy(ctx, businessID)
+	if err != nil {
+		return false, err
+	}
+	p, err := otp.NewPhoneNumber(phone)
+	if err != nil {
+		return false, err
+	}
+	return s.repo.Consume(ctx, businessID, p, func(stored otp.OTP) (bool, error) {
+		return s.svc.Verify(stored, businessID, p, code, policy)
+	})
+}
+
+// policy loads the business and returns its OTP policy, refusing businesses that
+// aren't active.
+func (s *OTPAppService) policy(ctx context.Context, id string) (otp.Policy, error) {
+	b, err := s.businesses.GetByID(ctx, id)
+	if err != nil {
+		return otp.Policy{}, err
+	}
+	if err := b.CheckActive(); err != nil {
+		return otp.Policy{}, err
+	}
+	return OTPPolicy(b)
+}
+
+func newRequestID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
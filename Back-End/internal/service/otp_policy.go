@@ -0,0 +1,31 @@
+package service
+
+import (
+	"github.com/panbeh/otp-backend/internal/domain/business"
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+)
+
+// OTPPolicy maps a business's OTP settings onto the domain policy. Settings the business
+// hasn't overridden stay zero, so otp.Service falls back to the deployment defaults.
+func OTPPolicy(b business.Business) (otp.Policy, error) {
+	var policy otp.Policy
+	var err error
+
+	if len(b.AllowedCountries) > 0 {
+		if policy.Countries, err = otp.NewCountryAllowlist(b.AllowedCountries); err != nil {
+			return otp.Policy{}, err
+		}
+	}
+	if b.CodeLength != 0 || b.CodeCharset != "" {
+		if policy.CodeFormat, err = otp.NewCodeFormat(b.CodeLength, otp.CodeCharset(b.CodeCharset)); err != nil {
+			return otp.Policy{}, err
+		}
+	}
+	if b.OTPTTL != 0 {
+		if policy.TTL, err = otp.NewCodeTTL(b.OTPTTL); err != nil {
+			return otp.Policy{}, err
+		}
+	}
+	policy.Suspended = b.Status == business.StatusSuspended
+	return policy, nil
+}
@@ -0,0 +1,35 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/business"
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+	"github.com/panbeh/otp-backend/internal/service"
+)
+
+func TestOTPPolicy(t *testing.T) {
+	policy, err := service.OTPPolicy(business.Business{Status: business.StatusActive})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(policy.Countries) != 0 || !policy.CodeFormat.IsZero() || policy.TTL != 0 || policy.Suspended {
+		t.Fatalf("expected defaults for a business without overrides, got %+v", policy)
+	}
+
+	policy, err = service.OTPPolicy(business.Business{
+		Status:           business.StatusSuspended,
+		AllowedCountries: []string{"98", "971"},
+		CodeLength:       8,
+		CodeCharset:      "alphanumeric",
+		OTPTTL:           30 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(policy.Countries) != 2 || policy.CodeFormat != (otp.CodeFormat{Length: 8, Charset: otp.CodeCharsetAlphanumeric}) ||
+		time.Duration(policy.TTL) != 30*time.Second || !policy.Suspended {
+		t.Fatalf("unexpected policy: %+v", policy)
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/business"
+)
+
+// OTPSettingsService reads and changes a business's OTP settings. Each change is
+// validated by the business domain and applies to the next code the business sends.
+type OTPSettingsService struct {
+	repo business.Repository
+	svc  *business.Service
+}
+
+func NewOTPSettingsService(repo business.Repository, svc *business.Service) *OTPSettingsService {
+	return &OTPSettingsService{repo: repo, svc: svc}
+}
+
+// Get returns the business with its current OTP settings.
+func (s *OTPSettingsService) Get(ctx context.Context, businessID string) (business.Business, error) {
+	return s.repo.GetByID(ctx, businessID)
+}
+
+// SetAllowedCountries replaces the business's country allowlist; an empty list allows
+// the deployment's default countries again.
+func (s *OTPSettingsService) SetAllowedCountries(ctx context.Context, businessID string, codes []string) (business.Business, error) {
+	b, err := s.repo.GetByID(ctx, businessID)
+	if err != nil {
+		return business.Business{}, err
+	}
+	b, err = s.svc.SetAllowedCountries(b, codes)
+	if err != nil {
+		return business.Business{}, err
+	}
+	if err := s.repo.UpdateAllowedCountries(ctx, b.ID, b.AllowedCountries); err != nil {
+		return business.Business{}, err
+	}
+	return b, nil
+}
+
+// SetCodeFormat changes the length and charset of the business's codes; 0 and ""
+// restore the deployment default. Codes still pending in the old format no longer verify.
+func (s *OTPSettingsService) SetCodeFormat(ctx context.Context, businessID string, length int, charset string) (business.Business, error) {
+	b, err := s.repo.GetByID(ctx, businessID)
+	if err != nil {
+		return business.Business{}, err
+	}
+	b, err = s.svc.SetCodeFormat(b, length, charset)
+	if err != nil {
+		return business.Business{}, err
+	}
+	if err := s.repo.UpdateCodeFormat(ctx, b.ID, b.CodeLength, b.CodeCharset); err != nil {
+		return business.Business{}, err
+	}
+	return b, nil
+}
+
+// SetOTPTTL changes how long the business's codes stay valid; zero restores the
+// deployment default. Codes already sent keep their expiry.
+func (s *OTPSettingsService) SetOTPTTL(ctx context.Context, businessID string, ttl time.Duration) (business.Business, error) {
+	b, err := s.repo.GetByID(ctx, businessID)
+	if err != nil {
+		return business.Business{}, err
+	}
+	b, err = s.svc.SetOTPTTL(b, ttl)
+	if err != nil {
+		return business.Business{}, err
+	}
+	if err := s.repo.UpdateOTPTTL(ctx, b.ID, b.OTPTTL); err != nil {
+		return business.Business{}, err
+	}
+	return b, nil
+}
@@ -0,0 +1,92 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/business"
+	"github.com/panbeh/otp-backend/internal/service"
+)
+
+func (f *fakeBusinesses) UpdateAllowedCountries(ctx context.Context, id string, codes []string) error {
+	b := f.businesses[id]
+	b.AllowedCountries = codes
+	f.businesses[id] = b
+	return nil
+}
+
+func (f *fakeBusinesses) UpdateCodeFormat(ctx context.Context, id string, length int, charset string) error {
+	b := f.businesses[id]
+	b.CodeLength, b.CodeCharset = length, charset
+	f.businesses[id] = b
+	return nil
+}
+
+func (f *fakeBusinesses) UpdateOTPTTL(ctx context.Context, id string, ttl time.Duration) error {
+	b := f.businesses[id]
+	b.OTPTTL = ttl
+	f.businesses[id] = b
+	return nil
+}
+
+func newOTPSettingsService() (*service.OTPSettingsService, *fakeBusinesses) {
+	repo := &fakeBusinesses{businesses: map[string]business.Business{"b1": {ID: "b1"}}}
+	return service.NewOTPSettingsService(repo, business.NewService(business.ServiceConfig{})), repo
+}
+
+func TestOTPSettingsService_SetAllowedCountries(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newOTPSettingsService()
+
+	b, err := svc.SetAllowedCountries(ctx, "b1", []string{"+98", "971", "98"})
+	if err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	want := []string{"98", "971"}
+	if !reflect.DeepEqual(b.AllowedCountries, want) || !reflect.DeepEqual(repo.businesses["b1"].AllowedCountries, want) {
+		t.Fatalf("expected %v stored, got %v (repo %v)", want, b.AllowedCountries, repo.businesses["b1"].AllowedCountries)
+	}
+
+	if _, err := svc.SetAllowedCountries(ctx, "b1", []string{"0"}); !errors.Is(err, business.ErrInvalidCountryCode) {
+		t.Fatalf("expected ErrInvalidCountryCode, got %v", err)
+	}
+	if !reflect.DeepEqual(repo.businesses["b1"].AllowedCountries, want) {
+		t.Fatalf("expected the allowlist unchanged after a rejected update, got %v", repo.businesses["b1"].AllowedCountries)
+	}
+	if _, err := svc.SetAllowedCountries(ctx, "b2", []string{"98"}); !errors.Is(err, business.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for an unknown business, got %v", err)
+	}
+}
+
+func TestOTPSettingsService_SetCodeFormat(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newOTPSettingsService()
+
+	if _, err := svc.SetCodeFormat(ctx, "b1", 8, "Alphanumeric"); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if b := repo.businesses["b1"]; b.CodeLength != 8 || b.CodeCharset != "alphanumeric" {
+		t.Fatalf("expected 8 alphanumeric stored, got %d %q", b.CodeLength, b.CodeCharset)
+	}
+	if _, err := svc.SetCodeFormat(ctx, "b1", 11, "numeric"); !errors.Is(err, business.ErrInvalidCodeFormat) {
+		t.Fatalf("expected ErrInvalidCodeFormat, got %v", err)
+	}
+}
+
+func TestOTPSettingsService_SetOTPTTL(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newOTPSettingsService()
+
+	if _, err := svc.SetOTPTTL(ctx, "b1", 90*time.Second); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if got := repo.businesses["b1"].OTPTTL; got != 90*time.Second {
+		t.Fatalf("expected 90s stored, got %v", got)
+	}
+	if _, err := svc.SetOTPTTL(ctx, "b1", -time.Second); !errors.Is(err, business.ErrInvalidOTPTTL) {
+		t.Fatalf("expected ErrInvalidOTPTTL, got %v", err)
+	}
+}
@@ -0,0 +1,273 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/business"
+	"github.com/panbeh/otp-backend/internal/domain/delivery"
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+	"github.com/panbeh/otp-backend/internal/service"
+	"github.com/panbeh/otp-backend/internal/sms"
+)
+
+// fakeOTPRepo accepts every save and holds the code "123456", hashed with the zero
+// hasher, until it is consumed; like the Redis repository it locks after two wrong
+// attempts. With missing set it behaves as if the code had expired. Other methods are unused.
+type fakeOTPRepo struct {
+	otp.Repository
+	missing  bool
+	consumed bool
+	saved    int
+	released int
+	attempts int
+}
+
+func (f *fakeOTPRepo) Save(ctx context.Context, o otp.OTP) error {
+	f.saved++
+	f.consumed, f.attempts = false, 0
+	return nil
+}
+
+func (f *fakeOTPRepo) Release(ctx context.Context, o otp.OTP) error {
+	f.released++
+	return nil
+}
+
+func (f *fakeOTPRepo) Consume(ctx context.Context, businessID string, phone otp.PhoneNumber, check otp.CodeCheck) (bool, error) {
+	if f.missing || f.consumed {
+		return check(otp.OTP{})
+	}
+	ok, err := check(otp.OTP{
+		BusinessID:  businessID,
+		PhoneNumber: phone,
+		CodeHash:    otp.CodeHasher{}.Hash(businessID, phone, "123456"),
+		ExpiresAt:   time.Now().Add(time.Minute),
+		Attempts:    f.attempts,
+		MaxAttempts: 2,
+	})
+	switch {
+	case err != nil:
+	case ok:
+		f.consumed = true
+	default:
+		f.attempts++
+		if f.attempts >= 2 {
+			return false, otp.ErrTooManyAttempts
+		}
+	}
+	return ok, err
+}
+
+func (f *fakeOTPRepo) Get(ctx context.Context, businessID string, phone otp.PhoneNumber) (otp.OTP, error) {
+	if f.missing || f.consumed {
+		return otp.OTP{}, otp.ErrNotFound
+	}
+	return otp.OTP{BusinessID: businessID, PhoneNumber: phone}, nil
+}
+
+// fakeSMS records the codes it is asked to deliver and the requests they were tagged
+// with, and fails them all with err.
+type fakeSMS struct {
+	err      error
+	codes    []string
+	requests []sms.Request
+}
+
+func (f *fakeSMS) Send(ctx context.Context, phone otp.PhoneNumber, code string) error {
+	f.codes = append(f.codes, code)
+	if req, ok := sms.RequestFromContext(ctx); ok {
+		f.requests = append(f.requests, req)
+	}
+	return f.err
+}
+
+// fakeDeliveries holds a single delivery of business b1; other methods are unused.
+type fakeDeliveries struct {
+	delivery.Repository
+	d delivery.Delivery
+}
+
+func (f *fakeDeliveries) Get(ctx context.Context, businessID, id string) (delivery.Delivery, error) {
+	if businessID != f.d.BusinessID || id != f.d.ID {
+		return delivery.Delivery{}, delivery.ErrNotFound
+	}
+	return f.d, nil
+}
+
+// fakeBusinesses keeps businesses by ID and sealed signing secrets in memory; other
+// methods are unused.
+type fakeBusinesses struct {
+	business.Repository
+	businesses map[string]business.Business
+	secrets    map[string]business.SigningSecret
+}
+
+func (f *fakeBusinesses) GetByID(ctx context.Context, id string) (business.Business, error) {
+	b, ok := f.businesses[id]
+	if !ok {
+		return business.Business{}, business.ErrNotFound
+	}
+	return b, nil
+}
+
+func (f *fakeBusinesses) GetByToken(ctx context.Context, token string) (business.Business, error) {
+	for _, b := range f.businesses {
+		if b.TokenHash == business.HashToken(token) {
+			return b, nil
+		}
+	}
+	return business.Business{}, business.ErrNotFound
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func newOTPAppService(sender *fakeSMS) (*service.OTPAppService, *fakeOTPRepo) {
+	return newOTPAppServiceFor(business.Business{ID: "b1"}, sender)
+}
+
+func newOTPAppServiceFor(b business.Business, sender *fakeSMS) (*service.OTPAppService, *fakeOTPRepo) {
+	businesses := &fakeBusinesses{businesses: map[string]business.Business{b.ID: b}}
+	repo := &fakeOTPRepo{}
+	svc := otp.NewService(otp.ServiceConfig{CodeGen: func() (string, error) { return "123456", nil }})
+	deliveries := &fakeDeliveries{d: delivery.Delivery{ID: "r1", BusinessID: "b1", Status: delivery.StatusSent}}
+	return service.NewOTPAppService(businesses, repo, svc, sender, deliveries, discardLogger()), repo
+}
+
+func TestOTPAppService_SendAndVerify(t *testing.T) {
+	ctx := context.Background()
+	sender := &fakeSMS{}
+	svc, _ := newOTPAppService(sender)
+
+	sent, err := svc.Send(ctx, "b1", "09123456789")
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if len(sender.codes) != 1 || sender.codes[0] != "123456" {
+		t.Fatalf("expected the code to be delivered, got %v", sender.codes)
+	}
+	if len(sender.requests) != 1 || sender.requests[0] != (sms.Request{ID: sent.RequestID, BusinessID: "b1"}) || sent.RequestID == "" {
+		t.Fatalf("expected the send tagged with request %q of b1, got %v", sent.RequestID, sender.requests)
+	}
+	if ok, err := svc.Verify(ctx, "b1", "09123456789", " 123456 "); !ok || err != nil {
+		t.Fatalf("expected the code to verify, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestOTPAppService_SuspendedBusiness(t *testing.T) {
+	ctx := context.Background()
+	sender := &fakeSMS{}
+	svc, repo := newOTPAppServiceFor(business.Business{ID: "b1", Status: business.StatusSuspended}, sender)
+
+	if _, err := svc.Send(ctx, "b1", "09123456789"); !errors.Is(err, business.ErrSuspended) {
+		t.Fatalf("expected ErrSuspended sending, got %v", err)
+	}
+	if _, err := svc.Verify(ctx, "b1", "09123456789", "123456"); !errors.Is(err, business.ErrSuspended) {
+		t.Fatalf("expected ErrSuspended verifying, got %v", err)
+	}
+	if repo.saved != 0 || repo.attempts != 0 || len(sender.codes) != 0 {
+		t.Fatalf("expected nothing stored, consumed or sent, got saved=%d attempts=%d sent=%d",
+			repo.saved, repo.attempts, len(sender.codes))
+	}
+}
+
+func TestOTPAppService_FailedSendReleasesCode(t *testing.T) {
+	errGateway := errors.New("gateway down")
+	svc, repo := newOTPAppService(&fakeSMS{err: errGateway})
+
+	if _, err := svc.Send(context.Background(), "b1", "09123456789"); !errors.Is(err, errGateway) {
+		t.Fatalf("expected the sender's error, got %v", err)
+	}
+	if repo.saved != 1 || repo.released != 1 {
+		t.Fatalf("expected the undelivered code released, got saved=%d released=%d", repo.saved, repo.released)
+	}
+}
+
+func TestOTPAppService_Verify_TooManyAttempts(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newOTPAppService(&fakeSMS{})
+
+	if ok, err := svc.Verify(ctx, "b1", "09123456789", "000000"); ok || err != nil {
+		t.Fatalf("expected a plain mismatch, got ok=%v err=%v", ok, err)
+	}
+	if _, err := svc.Verify(ctx, "b1", "09123456789", "000000"); !errors.Is(err, otp.ErrTooManyAttempts) {
+		t.Fatalf("expected the last wrong code to lock the OTP, got %v", err)
+	}
+	if _, err := svc.Verify(ctx, "b1", "09123456789", "123456"); !errors.Is(err, otp.ErrTooManyAttempts) {
+		t.Fatalf("expected ErrTooManyAttempts once locked, got %v", err)
+	}
+	if _, err := svc.Verify(ctx, "b1", "09123456789", "12ab"); !errors.Is(err, otp.ErrInvalidCode) {
+		t.Fatalf("expected ErrInvalidCode for a malformed code, got %v", err)
+	}
+}
+
+func TestOTPAppService_EnforcesAllowedCountries(t *testing.T) {
+	ctx := context.Background()
+	sender := &fakeSMS{}
+	svc, repo := newOTPAppServiceFor(business.Business{ID: "b1", AllowedCountries: []string{"971"}}, sender)
+
+	if _, err := svc.Send(ctx, "b1", "+989123456789"); !errors.Is(err, otp.ErrCountryNotAllowed) {
+		t.Fatalf("expected ErrCountryNotAllowed outside the allowlist, got %v", err)
+	}
+	if repo.saved != 0 || len(sender.codes) != 0 {
+		t.Fatalf("expected nothing stored or sent, got saved=%d sent=%d", repo.saved, len(sender.codes))
+	}
+	if _, err := svc.Send(ctx, "b1", "+971501234567"); err != nil {
+		t.Fatalf("expected an allowed country to send, got %v", err)
+	}
+}
+
+func TestOTPAppService_AppliesCodeFormat(t *testing.T) {
+	ctx := context.Background()
+	businesses := &fakeBusinesses{businesses: map[string]business.Business{
+		"b1": {ID: "b1", CodeLength: 8, CodeCharset: "alphanumeric"},
+	}}
+	sender := &fakeSMS{}
+	svc := service.NewOTPAppService(businesses, &fakeOTPRepo{}, otp.NewService(otp.ServiceConfig{}), sender, &fakeDeliveries{}, discardLogger())
+
+	if _, err := svc.Send(ctx, "b1", "09123456789"); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	format, _ := otp.NewCodeFormat(8, otp.CodeCharsetAlphanumeric)
+	if len(sender.codes) != 1 || !format.Matches(sender.codes[0]) {
+		t.Fatalf("expected an 8-character alphanumeric code, got %v", sender.codes)
+	}
+	if _, err := svc.Verify(ctx, "b1", "09123456789", "123456"); !errors.Is(err, otp.ErrInvalidCode) {
+		t.Fatalf("expected ErrInvalidCode for a code outside the business's format, got %v", err)
+	}
+}
+
+func TestOTPAppService_AppliesTTL(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	businesses := &fakeBusinesses{businesses: map[string]business.Business{
+		"b1": {ID: "b1", OTPTTL: 45 * time.Second},
+	}}
+	otps := otp.NewService(otp.ServiceConfig{Now: func() time.Time { return now }, TTL: otp.CodeTTL(5 * time.Minute)})
+	svc := service.NewOTPAppService(businesses, &fakeOTPRepo{}, otps, &fakeSMS{}, &fakeDeliveries{}, discardLogger())
+
+	sent, err := svc.Send(context.Background(), "b1", "09123456789")
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if want := now.Add(45 * time.Second); !sent.ExpiresAt.Equal(want) {
+		t.Fatalf("expected the business's ttl to set the expiry %v, got %v", want, sent.ExpiresAt)
+	}
+}
+
+func TestOTPAppService_Request(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newOTPAppService(&fakeSMS{})
+
+	if d, err := svc.Request(ctx, "b1", "r1"); err != nil || d.Status != delivery.StatusSent {
+		t.Fatalf("expected the delivery, got %#v err=%v", d, err)
+	}
+	if _, err := svc.Request(ctx, "b2", "r1"); !errors.Is(err, delivery.ErrNotFound) {
+		t.Fatalf("expected another business's request to be not found, got %v", err)
+	}
+}
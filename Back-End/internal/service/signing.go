@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/apikey"
+	"github.com/panbeh/otp-backend/internal/domain/business"
+)
+
+var ErrInvalidSealedSecret = errors.New("service: invalid sealed secret")
+
+const (
+	minNonceLen = 16
+	maxNonceLen = 128
+)
+
+// SecretSealer encrypts business secrets that, unlike API tokens, have to be
+// recoverable because the server recomputes HMACs with them.
+type SecretSealer struct {
+	aead cipher.AEAD
+}
+
+func NewSecretSealer(secret []byte) (SecretSealer, error) {
+	// Derive a dedicated key so sealed secrets never share key material with other uses of secret.
+	key := sha256.Sum256(append([]byte("business-signing:"), secret...))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return SecretSealer{}, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return SecretSealer{}, err
+	}
+	return SecretSealer{aead: aead}, nil
+}
+
+func (s SecretSealer) Seal(secret string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawStdEncoding.EncodeToString(s.aead.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+func (s SecretSealer) Open(sealed string) (string, error) {
+	b, err := base64.RawStdEncoding.DecodeString(sealed)
+	if err != nil || len(b) < s.aead.NonceSize() {
+		return "", ErrInvalidSealedSecret
+	}
+	nonce, ciphertext := b[:s.aead.NonceSize()], b[s.aead.NonceSize():]
+	secret, err := s.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrInvalidSealedSecret
+	}
+	return string(secret), nil
+}
+
+type NonceStore interface {
+	Claim(ctx context.Context, businessID, nonce string, ttl time.Duration) (bool, error)
+}
+
+// SignedRequest is what a client signed, as received by the server.
+type SignedRequest struct {
+	BusinessID string
+	Method     string
+	// Path includes the query string, so query parameters are covered by the signature.
+	Path      string
+	Timestamp string
+	Nonce     string
+	Body      []byte
+	Signature string
+}
+
+// SigningPayload is the string clients HMAC-SHA256 with their signing secret:
+// method, path, unix timestamp, nonce and hex SHA-256 of the body, newline-separated.
+func SigningPayload(method, path, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	return strings.Join([]string{strings.ToUpper(method), path, timestamp, nonce, hex.EncodeToString(bodyHash[:])}, "\n")
+}
+
+// Sign returns the hex signature of payload, as clients are expected to compute it.
+func Sign(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// RequestSigningService issues signing secrets and authenticates HMAC-signed requests.
+// Each secret is tied to one of the business's API keys, and signed requests act with
+// that key's scopes.
+type RequestSigningService struct {
+	businesses business.Repository
+	keys       apikey.Repository
+	svc        *business.Service
+	sealer     SecretSealer
+	nonces     NonceStore
+	maxSkew    time.Duration
+	now        func() time.Time
+}
+
+func NewRequestSigningService(businesses business.Repository, keys apikey.Repository, svc *business.Service, sealer SecretSealer, nonces NonceStore, maxSkew time.Duration) *RequestSigningService {
+	return &RequestSigningService{businesses: businesses, keys: keys, svc: svc, sealer: sealer, nonces: nonces, maxSkew: maxSkew, now: time.Now}
+}
+
+// IssueSecret replaces the signing secret of issuer's business with one tied to the
+// API key keyID and returns it. This is the only time the raw secret is available.
+// Like creating a key, it refuses with apikey.ErrScopeDenied a key holding scopes
+// issuer lacks; a revoked key fails with apikey.ErrRevoked.
+func (s *RequestSigningService) IssueSecret(ctx context.Context, issuer apikey.APIKey, keyID string) (string, error) {
+	k, err := s.keys.GetByID(ctx, issuer.BusinessID, keyID)
+	if err != nil {
+		return "", err
+	}
+	if k.Revoked() {
+		return "", apikey.ErrRevoked
+	}
+	if !issuer.HasScopes(k.Scopes) {
+		return "", apikey.ErrScopeDenied
+	}
+
+	secret, err := s.svc.NewSigningSecret()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := s.sealer.Seal(secret)
+	if err != nil {
+		return "", err
+	}
+	if err := s.businesses.SetSigningSecret(ctx, issuer.BusinessID, business.SigningSecret{KeyID: k.ID, Sealed: sealed}); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// Verify authenticates a signed request. The timestamp must be within maxSkew of the
+// server clock and each nonce is accepted once; nonces are remembered for 2*maxSkew,
+// which covers every timestamp that could still pass the skew check. A valid signature
+// acts as the API key its secret is tied to and fails with apikey.ErrRevoked once that
+// key is revoked. Requests from a suspended business fail with business.ErrSuspended.
+func (s *RequestSigningService) Verify(ctx context.Context, r SignedRequest) (apikey.APIKey, error) {
+	if strings.TrimSpace(r.BusinessID) == "" || r.Signature == "" || len(r.Nonce) < minNonceLen || len(r.Nonce) > maxNonceLen {
+		return apikey.APIKey{}, apikey.ErrInvalidSignature
+	}
+	ts, err := parseUnix(r.Timestamp)
+	if err != nil {
+		return apikey.APIKey{}, apikey.ErrInvalidSignature
+	}
+	if skew := s.now().Sub(ts); skew > s.maxSkew || skew < -s.maxSkew {
+		return apikey.APIKey{}, apikey.ErrStaleSignature
+	}
+
+	stored, err := s.businesses.GetSigningSecret(ctx, r.BusinessID)
+	if errors.Is(err, business.ErrNotFound) || (err == nil && stored.Sealed == "") {
+		return apikey.APIKey{}, apikey.ErrInvalidSignature
+	}
+	if err != nil {
+		return apikey.APIKey{}, err
+	}
+	secret, err := s.sealer.Open(stored.Sealed)
+	if err != nil {
+		return apikey.APIKey{}, err
+	}
+
+	expected := Sign(secret, SigningPayload(r.Method, r.Path, r.Timestamp, r.Nonce, r.Body))
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(r.Signature))) {
+		return apikey.APIKey{}, apikey.ErrInvalidSignature
+	}
+
+	b, err := s.businesses.GetByID(ctx, r.BusinessID)
+	if err != nil {
+		return apikey.APIKey{}, err
+	}
+	if err := b.CheckActive(); err != nil {
+		if errors.Is(err, business.ErrNotFound) {
+			return apikey.APIKey{}, apikey.ErrInvalidSignature
+		}
+		return apikey.APIKey{}, err
+	}
+	k, err := s.signingKey(ctx, r.BusinessID, stored.KeyID)
+	if err != nil {
+		return apikey.APIKey{}, err
+	}
+
+	// The nonce is only claimed once the signature checks out, so forged requests
+	// can't burn nonces a legitimate client is about to use.
+	fresh, err := s.nonces.Claim(ctx, r.BusinessID, r.Nonce, 2*s.maxSkew)
+	if err != nil {
+		return apikey.APIKey{}, err
+	}
+	if !fresh {
+		return apikey.APIKey{}, apikey.ErrReplayedNonce
+	}
+	return k, nil
+}
+
+// signingKey returns the API key a signing secret acts as. Secrets issued before they
+// were tied to a key only get the OTP scopes, never admin ones.
+func (s *RequestSigningService) signingKey(ctx context.Context, businessID, keyID string) (apikey.APIKey, error) {
+	if keyID == "" {
+		return apikey.APIKey{BusinessID: businessID, Name: "signed request", Scopes: apikey.OTPScopes}, nil
+	}
+	k, err := s.keys.GetByID(ctx, businessID, keyID)
+	if err != nil {
+		return apikey.APIKey{}, err
+	}
+	if k.Revoked() {
+		return apikey.APIKey{}, apikey.ErrRevoked
+	}
+	return k, nil
+}
+
+func parseUnix(v string) (time.Time, error) {
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || sec < 0 {
+		return time.Time{}, apikey.ErrInvalidSignature
+	}
+	return time.Unix(sec, 0), nil
+}
@@ -0,0 +1,211 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/apikey"
+	"github.com/panbeh/otp-backend/internal/domain/business"
+	"github.com/panbeh/otp-backend/internal/service"
+)
+
+func (f *fakeBusinesses) SetSigningSecret(ctx context.Context, id string, s business.SigningSecret) error {
+	f.secrets[id] = s
+	return nil
+}
+
+func (f *fakeBusinesses) GetSigningSecret(ctx context.Context, id string) (business.SigningSecret, error) {
+	s, ok := f.secrets[id]
+	if !ok {
+		return business.SigningSecret{}, business.ErrNotFound
+	}
+	return s, nil
+}
+
+func (f *fakeKeyRepo) GetByID(ctx context.Context, businessID, id string) (apikey.APIKey, error) {
+	for _, k := range f.keys {
+		if k.ID == id && k.BusinessID == businessID {
+			return k, nil
+		}
+	}
+	return apikey.APIKey{}, apikey.ErrNotFound
+}
+
+// owner is the business's full-access key, which issues the signing secrets.
+var owner = apikey.APIKey{BusinessID: "b1", Scopes: apikey.AllScopes}
+
+type memNonces map[string]bool
+
+func (m memNonces) Claim(ctx context.Context, businessID, nonce string, ttl time.Duration) (bool, error) {
+	key := businessID + ":" + nonce
+	if m[key] {
+		return false, nil
+	}
+	m[key] = true
+	return true, nil
+}
+
+func newSigningService(t *testing.T) (*service.RequestSigningService, string) {
+	t.Helper()
+	svc, secret, _ := newSigningServiceWithRepo(t)
+	return svc, secret
+}
+
+func newSigningServiceWithRepo(t *testing.T) (*service.RequestSigningService, string, *fakeBusinesses) {
+	t.Helper()
+	svc, repo, _ := newSigningServiceWithKeys(t)
+	secret, err := svc.IssueSecret(context.Background(), owner, "k-otp")
+	if err != nil {
+		t.Fatalf("issue secret: %v", err)
+	}
+	return svc, secret, repo
+}
+
+func newSigningServiceWithKeys(t *testing.T) (*service.RequestSigningService, *fakeBusinesses, *fakeKeyRepo) {
+	t.Helper()
+	repo := &fakeBusinesses{
+		businesses: map[string]business.Business{"b1": {ID: "b1", Status: business.StatusActive}},
+		secrets:    map[string]business.SigningSecret{},
+	}
+	keys := &fakeKeyRepo{keys: map[string]apikey.APIKey{
+		"otp":   {ID: "k-otp", BusinessID: "b1", Scopes: apikey.OTPScopes},
+		"admin": {ID: "k-admin", BusinessID: "b1", Scopes: []apikey.Scope{apikey.ScopeAdminRead, apikey.ScopeAdminWrite}},
+		"other": {ID: "k-other", BusinessID: "b2", Scopes: apikey.OTPScopes},
+	}}
+	sealer, err := service.NewSecretSealer([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("sealer: %v", err)
+	}
+	svc := service.NewRequestSigningService(
+		repo, keys,
+		business.NewService(business.ServiceConfig{}),
+		sealer, memNonces{}, 5*time.Minute,
+	)
+	return svc, repo, keys
+}
+
+func signedRequest(secret string, ts time.Time, nonce, body string) service.SignedRequest {
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	return service.SignedRequest{
+		BusinessID: "b1",
+		Method:     "POST",
+		Path:       "/otp/send?lang=fa",
+		Timestamp:  timestamp,
+		Nonce:      nonce,
+		Body:       []byte(body),
+		Signature:  service.Sign(secret, service.SigningPayload("POST", "/otp/send?lang=fa", timestamp, nonce, []byte(body))),
+	}
+}
+
+func TestRequestSigningService_Verify(t *testing.T) {
+	ctx := context.Background()
+	svc, secret := newSigningService(t)
+
+	r := signedRequest(secret, time.Now(), "nonce-0000000001", `{"phone":"09123456789"}`)
+	k, err := svc.Verify(ctx, r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if k.BusinessID != "b1" || k.ID != "k-otp" || !k.HasScope(apikey.ScopeOTPSend) || k.HasScope(apikey.ScopeAdminWrite) {
+		t.Fatalf("expected the scopes of key k-otp, got %#v", k)
+	}
+
+	if _, err := svc.Verify(ctx, r); !errors.Is(err, apikey.ErrReplayedNonce) {
+		t.Fatalf("expected ErrReplayedNonce on replay, got %v", err)
+	}
+}
+
+func TestRequestSigningService_Verify_Rejects(t *testing.T) {
+	ctx := context.Background()
+	svc, secret := newSigningService(t)
+
+	tampered := signedRequest(secret, time.Now(), "nonce-0000000002", `{"phone":"09123456789"}`)
+	tampered.Body = []byte(`{"phone":"09120000000"}`)
+	if _, err := svc.Verify(ctx, tampered); !errors.Is(err, apikey.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature for tampered body, got %v", err)
+	}
+
+	stale := signedRequest(secret, time.Now().Add(-10*time.Minute), "nonce-0000000003", "")
+	if _, err := svc.Verify(ctx, stale); !errors.Is(err, apikey.ErrStaleSignature) {
+		t.Fatalf("expected ErrStaleSignature, got %v", err)
+	}
+
+	wrongKey := signedRequest("not-the-secret", time.Now(), "nonce-0000000004", "")
+	if _, err := svc.Verify(ctx, wrongKey); !errors.Is(err, apikey.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature for wrong secret, got %v", err)
+	}
+
+	unknown := signedRequest(secret, time.Now(), "nonce-0000000005", "")
+	unknown.BusinessID = "b2"
+	if _, err := svc.Verify(ctx, unknown); !errors.Is(err, apikey.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature for unknown business, got %v", err)
+	}
+
+	// A rejected request must not burn the nonce for the legitimate one.
+	if _, err := svc.Verify(ctx, signedRequest(secret, time.Now(), "nonce-0000000004", "")); err != nil {
+		t.Fatalf("expected nonce of forged request to stay usable, got %v", err)
+	}
+}
+
+func TestRequestSigningService_Verify_SuspendedBusiness(t *testing.T) {
+	svc, secret, repo := newSigningServiceWithRepo(t)
+	repo.businesses["b1"] = business.Business{ID: "b1", Status: business.StatusSuspended}
+
+	_, err := svc.Verify(context.Background(), signedRequest(secret, time.Now(), "nonce-0000000001", ""))
+	if !errors.Is(err, business.ErrSuspended) {
+		t.Fatalf("expected ErrSuspended, got %v", err)
+	}
+}
+
+func TestRequestSigningService_IssueSecret_Rejects(t *testing.T) {
+	ctx := context.Background()
+	svc, _, _ := newSigningServiceWithKeys(t)
+	otpOnly := apikey.APIKey{BusinessID: "b1", Scopes: apikey.OTPScopes}
+
+	if _, err := svc.IssueSecret(ctx, otpOnly, "k-admin"); !errors.Is(err, apikey.ErrScopeDenied) {
+		t.Fatalf("expected ErrScopeDenied for a stronger key, got %v", err)
+	}
+	if _, err := svc.IssueSecret(ctx, owner, "k-other"); !errors.Is(err, apikey.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for another business's key, got %v", err)
+	}
+}
+
+func TestRequestSigningService_Verify_RevokedKey(t *testing.T) {
+	svc, _, keys := newSigningServiceWithKeys(t)
+	secret, err := svc.IssueSecret(context.Background(), owner, "k-otp")
+	if err != nil {
+		t.Fatalf("issue secret: %v", err)
+	}
+	revoked := keys.keys["otp"]
+	at := time.Now()
+	revoked.RevokedAt = &at
+	keys.keys["otp"] = revoked
+
+	_, err = svc.Verify(context.Background(), signedRequest(secret, time.Now(), "nonce-0000000001", ""))
+	if !errors.Is(err, apikey.ErrRevoked) {
+		t.Fatalf("expected ErrRevoked, got %v", err)
+	}
+}
+
+func TestRequestSigningService_Verify_UntiedSecret(t *testing.T) {
+	svc, repo, _ := newSigningServiceWithKeys(t)
+	secret, err := svc.IssueSecret(context.Background(), owner, "k-otp")
+	if err != nil {
+		t.Fatalf("issue secret: %v", err)
+	}
+	// Secrets issued before they were tied to a key have no key ID.
+	stored := repo.secrets["b1"]
+	stored.KeyID = ""
+	repo.secrets["b1"] = stored
+
+	k, err := svc.Verify(context.Background(), signedRequest(secret, time.Now(), "nonce-0000000001", ""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !k.HasScope(apikey.ScopeOTPVerify) || k.HasScope(apikey.ScopeAdminRead) || k.HasScope(apikey.ScopeAdminWrite) {
+		t.Fatalf("expected only the OTP scopes, got %v", k.Scopes)
+	}
+}
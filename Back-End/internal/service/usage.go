@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+	"github.com/panbeh/otp-backend/internal/domain/usage"
+	"github.com/panbeh/otp-backend/internal/sms"
+)
+
+const usageFlushBatch = 500
+
+// quotaCacheTTL is how long a business's plan quota is reused before it is read from
+// Postgres again, so plan changes take at most this long to apply to sends.
+const quotaCacheTTL = time.Minute
+
+// MeteredOTPRepository counts sends and verification outcomes per business, records
+// each one as a usage event, and refuses to store new OTPs once the business's plan
+// quota for the month is used up. Sends are counted by the sender MeterSends wraps,
+// once the code has been handed off, so a send the gateway refused isn't billed.
+type MeteredOTPRepository struct {
+	otp.Repository
+	counter usage.Counter
+	store   usage.Store
+	events  usage.EventStore
+	svc     *usage.Service
+	logger  *slog.Logger
+
+	mu     sync.Mutex
+	quotas map[string]cachedQuota
+}
+
+type cachedQuota struct {
+	quota     int64
+	expiresAt time.Time
+}
+
+func NewMeteredOTPRepository(repo otp.Repository, counter usage.Counter, store usage.Store, events usage.EventStore, svc *usage.Service, logger *slog.Logger) *MeteredOTPRepository {
+	return &MeteredOTPRepository{
+		Repository: repo,
+		counter:    counter,
+		store:      store,
+		events:     events,
+		svc:        svc,
+		logger:     logger,
+		quotas:     make(map[string]cachedQuota),
+	}
+}
+
+// Save returns an *otp.RateLimitError with reason monthly_quota, without storing
+// anything, when the business is over quota. The check and the increment are not
+// atomic, so concurrent sends may overshoot the quota by a few.
+func (r *MeteredOTPRepository) Save(ctx context.Context, o otp.OTP) error {
+	now := r.svc.Now()
+	quota, err := r.monthlyQuota(ctx, o.BusinessID, now)
+	if err != nil {
+		return err
+	}
+	if quota > 0 {
+		sent, err := r.counter.MonthSends(ctx, o.BusinessID, now)
+		if err != nil {
+			return err
+		}
+		if err := r.svc.CheckQuota(sent, quota); err != nil {
+			return err
+		}
+	}
+
+	return r.Repository.Save(ctx, o)
+}
+
+// MeterSends wraps the sender codes are handed to, counting a send for the business
+// of the request in ctx (see sms.WithRequest) each time next accepts a code.
+func (r *MeteredOTPRepository) MeterSends(next sms.Sender) sms.Sender {
+	return meteredSender{next: next, meter: r}
+}
+
+type meteredSender struct {
+	next  sms.Sender
+	meter *MeteredOTPRepository
+}
+
+func (s meteredSender) Send(ctx context.Context, phone otp.PhoneNumber, code string) error {
+	if err := s.next.Send(ctx, phone, code); err != nil {
+		return err
+	}
+	if req, ok := sms.RequestFromContext(ctx); ok {
+		s.meter.count(ctx, req.BusinessID, usage.EventSend, s.meter.svc.Now())
+	}
+	return nil
+}
+
+// monthlyQuota reads the business's quota through a short-lived cache, so a send
+// doesn't cost a Postgres query.
+func (r *MeteredOTPRepository) monthlyQuota(ctx context.Context, businessID string, now time.Time) (int64, error) {
+	r.mu.Lock()
+	cached, ok := r.quotas[businessID]
+	r.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.quota, nil
+	}
+
+	quota, err := r.store.MonthlySendQuota(ctx, businessID)
+	if err != nil {
+		return 0, err
+	}
+	r.mu.Lock()
+	r.quotas[businessID] = cachedQuota{quota: quota, expiresAt: now.Add(quotaCacheTTL)}
+	r.mu.Unlock()
+	return quota, nil
+}
+
+// Consume reports a rejected code as expired when nothing is pending for the phone any
+// more, and as a failed attempt otherwise.
+func (r *MeteredOTPRepository) Consume(ctx context.Context, businessID string, phone otp.PhoneNumber, check otp.CodeCheck) (bool, error) {
+	ok, err := r.Repository.Consume(ctx, businessID, phone, check)
+	switch {
+	case err == nil && ok:
+		r.count(ctx, businessID, usage.EventVerifySucceeded, r.svc.Now())
+	case err == nil:
+		event := usage.EventVerifyFailed
+		if _, getErr := r.Repository.Get(ctx, businessID, phone); errors.Is(getErr, otp.ErrNotFound) {
+			event = usage.EventExpired
+		}
+		r.count(ctx, businessID, event, r.svc.Now())
+	case errors.Is(err, otp.ErrTooManyAttempts):
+		r.count(ctx, businessID, usage.EventVerifyFailed, r.svc.Now())
+	}
+	return ok, err
+}
+
+// count never fails the request: a lost increment is cheaper than a lost OTP.
+func (r *MeteredOTPRepository) count(ctx context.Context, businessID string, event usage.Event, at time.Time) {
+	if event != usage.EventExpired {
+		if err := r.counter.Incr(ctx, businessID, event, at); err != nil {
+			r.logger.ErrorContext(ctx, "usage_count_failed",
+				slog.String("business_id", businessID), slog.String("event", string(event)), slog.Any("err", err))
+		}
+	}
+	if err := r.events.Record(ctx, businessID, event, at); err != nil {
+		r.logger.ErrorContext(ctx, "usage_event_failed",
+			slog.String("business_id", businessID), slog.String("event", string(event)), slog.Any("err", err))
+	}
+}
+
+// UsageFlusher periodically copies the Redis daily counters into usage_daily.
+type UsageFlusher struct {
+	counter  usage.Counter
+	store    usage.Store
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+func NewUsageFlusher(counter usage.Counter, store usage.Store, interval time.Duration, logger *slog.Logger) *UsageFlusher {
+	return &UsageFlusher{counter: counter, store: store, interval: interval, logger: logger}
+}
+
+// Run flushes every interval until ctx is done, then flushes once more so a clean
+// shutdown doesn't leave counts only in Redis.
+func (f *UsageFlusher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			finalCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			return f.Flush(finalCtx)
+		case <-ticker.C:
+			if err := f.Flush(ctx); err != nil {
+				f.logger.ErrorContext(ctx, "usage_flush_failed", slog.Any("err", err))
+			}
+		}
+	}
+}
+
+// Flush drains every dirty business-day. Rows that fail to write are marked dirty again.
+func (f *UsageFlusher) Flush(ctx context.Context) error {
+	for {
+		days, err := f.counter.PopDirty(ctx, usageFlushBatch)
+		if err != nil {
+			return err
+		}
+		if len(days) == 0 {
+			return nil
+		}
+		if err := f.store.UpsertDaily(ctx, days); err != nil {
+			if markErr := f.counter.MarkDirty(ctx, days); markErr != nil {
+				return errors.Join(err, markErr)
+			}
+			return err
+		}
+		if len(days) < usageFlushBatch {
+			return nil
+		}
+	}
+}
+
+// UsageReportService answers a business's questions about its own usage.
+type UsageReportService struct {
+	events usage.EventStore
+	svc    *usage.Service
+}
+
+func NewUsageReportService(events usage.EventStore, svc *usage.Service) *UsageReportService {
+	return &UsageReportService{events: events, svc: svc}
+}
+
+// Report returns one summary per day or month from..to (UTC days, both inclusive),
+// including buckets without any events.
+func (s *UsageReportService) Report(ctx context.Context, businessID string, from, to time.Time, granularity usage.Granularity) ([]usage.Summary, error) {
+	q, err := s.svc.NewReportQuery(businessID, from, to, granularity)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.events.Summarize(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return usage.Fill(q, rows), nil
+}
@@ -0,0 +1,240 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+	"github.com/panbeh/otp-backend/internal/domain/usage"
+	"github.com/panbeh/otp-backend/internal/service"
+	"github.com/panbeh/otp-backend/internal/sms"
+)
+
+type memCounter struct {
+	counts map[usage.Event]int64
+	dirty  []usage.Daily
+}
+
+func (c *memCounter) Incr(ctx context.Context, businessID string, event usage.Event, at time.Time) error {
+	c.counts[event]++
+	return nil
+}
+
+func (c *memCounter) MonthSends(ctx context.Context, businessID string, at time.Time) (int64, error) {
+	return c.counts[usage.EventSend], nil
+}
+
+func (c *memCounter) PopDirty(ctx context.Context, n int) ([]usage.Daily, error) {
+	out := c.dirty
+	c.dirty = nil
+	return out, nil
+}
+
+func (c *memCounter) MarkDirty(ctx context.Context, days []usage.Daily) error {
+	c.dirty = append(c.dirty, days...)
+	return nil
+}
+
+type memEvents struct {
+	recorded []usage.Event
+}
+
+func (m *memEvents) Record(ctx context.Context, businessID string, event usage.Event, at time.Time) error {
+	m.recorded = append(m.recorded, event)
+	return nil
+}
+
+func (m *memEvents) Summarize(ctx context.Context, q usage.ReportQuery) ([]usage.Summary, error) {
+	return []usage.Summary{{Period: q.From.AddDate(0, 0, 1), Sends: 4}}, nil
+}
+
+type memStore struct {
+	quota      int64
+	quotaReads int
+	failing    bool
+	flushed    []usage.Daily
+}
+
+func (s *memStore) UpsertDaily(ctx context.Context, days []usage.Daily) error {
+	if s.failing {
+		return errors.New("db down")
+	}
+	s.flushed = append(s.flushed, days...)
+	return nil
+}
+
+func (s *memStore) GetPlan(ctx context.Context, id string) (usage.Plan, error) {
+	return usage.Plan{}, usage.ErrPlanNotFound
+}
+
+func (s *memStore) MonthlySendQuota(ctx context.Context, businessID string) (int64, error) {
+	s.quotaReads++
+	return s.quota, nil
+}
+
+func TestMeteredOTPRepository_EnforcesQuotaAndCounts(t *testing.T) {
+	ctx := context.Background()
+	inner := &fakeOTPRepo{}
+	counter := &memCounter{counts: map[usage.Event]int64{}}
+	events := &memEvents{}
+	repo := service.NewMeteredOTPRepository(inner, counter, &memStore{quota: 2}, events,
+		usage.NewService(usage.ServiceConfig{}), discardLogger())
+
+	sender := repo.MeterSends(&fakeSMS{})
+	sendCtx := sms.WithRequest(ctx, sms.Request{ID: "r1", BusinessID: "b1"})
+
+	o := otp.OTP{BusinessID: "b1", PhoneNumber: "+989123456789"}
+	for i := 0; i < 2; i++ {
+		if err := repo.Save(ctx, o); err != nil {
+			t.Fatalf("save %d: %v", i, err)
+		}
+		if err := sender.Send(sendCtx, o.PhoneNumber, "123456"); err != nil {
+			t.Fatalf("send %d: %v", i, err)
+		}
+	}
+	err := repo.Save(ctx, o)
+	if !errors.Is(err, otp.ErrQuotaExceeded) {
+		t.Fatalf("expected quota error, got %v", err)
+	}
+	if inner.saved != 2 || counter.counts[usage.EventSend] != 2 {
+		t.Fatalf("expected the over-quota send not to be stored or counted, saved=%d counted=%d",
+			inner.saved, counter.counts[usage.EventSend])
+	}
+
+	_, _ = repo.Consume(ctx, "b1", o.PhoneNumber, codeCheck("b1", o.PhoneNumber, "123456"))
+	// A fresh code, stored past the quota check.
+	_ = inner.Save(ctx, o)
+	_, _ = repo.Consume(ctx, "b1", o.PhoneNumber, codeCheck("b1", o.PhoneNumber, "000000"))
+	_, _ = repo.Consume(ctx, "b1", o.PhoneNumber, codeCheck("b1", o.PhoneNumber, "000000"))
+	if counter.counts[usage.EventVerifySucceeded] != 1 || counter.counts[usage.EventVerifyFailed] != 2 {
+		t.Fatalf("unexpected verify counts: %v", counter.counts)
+	}
+	want := []usage.Event{usage.EventSend, usage.EventSend, usage.EventVerifySucceeded, usage.EventVerifyFailed, usage.EventVerifyFailed}
+	if len(events.recorded) != len(want) {
+		t.Fatalf("expected events %v, got %v", want, events.recorded)
+	}
+	for i := range want {
+		if events.recorded[i] != want[i] {
+			t.Fatalf("expected events %v, got %v", want, events.recorded)
+		}
+	}
+}
+
+func TestMeteredOTPRepository_BillsOnlyHandedOffSends(t *testing.T) {
+	ctx := context.Background()
+	counter := &memCounter{counts: map[usage.Event]int64{}}
+	events := &memEvents{}
+	repo := service.NewMeteredOTPRepository(&fakeOTPRepo{}, counter, &memStore{quota: 1}, events,
+		usage.NewService(usage.ServiceConfig{}), discardLogger())
+	sendCtx := sms.WithRequest(ctx, sms.Request{ID: "r1", BusinessID: "b1"})
+
+	o := otp.OTP{BusinessID: "b1", PhoneNumber: "+989123456789"}
+	if err := repo.Save(ctx, o); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	errGateway := errors.New("gateway down")
+	if err := repo.MeterSends(&fakeSMS{err: errGateway}).Send(sendCtx, o.PhoneNumber, "123456"); !errors.Is(err, errGateway) {
+		t.Fatalf("expected the sender's error, got %v", err)
+	}
+	if counter.counts[usage.EventSend] != 0 || len(events.recorded) != 0 {
+		t.Fatalf("expected a refused send not to be billed, got %v and %v", counter.counts, events.recorded)
+	}
+
+	// The quota is still available for the retry.
+	if err := repo.Save(ctx, o); err != nil {
+		t.Fatalf("expected the retry to be within quota, got %v", err)
+	}
+}
+
+func TestMeteredOTPRepository_ReportsMissingCodeAsExpired(t *testing.T) {
+	ctx := context.Background()
+	counter := &memCounter{counts: map[usage.Event]int64{}}
+	events := &memEvents{}
+	repo := service.NewMeteredOTPRepository(&fakeOTPRepo{missing: true}, counter, &memStore{}, events,
+		usage.NewService(usage.ServiceConfig{}), discardLogger())
+
+	if ok, err := repo.Consume(ctx, "b1", "+989123456789", codeCheck("b1", "+989123456789", "123456")); ok || err != nil {
+		t.Fatalf("expected plain rejection, got ok=%v err=%v", ok, err)
+	}
+	if len(events.recorded) != 1 || events.recorded[0] != usage.EventExpired {
+		t.Fatalf("expected an expired event, got %v", events.recorded)
+	}
+	if len(counter.counts) != 0 {
+		t.Fatalf("expected expirations to stay out of the daily counters, got %v", counter.counts)
+	}
+}
+
+// codeCheck verifies code the way OTPAppService does, with the default policy.
+func codeCheck(businessID string, phone otp.PhoneNumber, code string) otp.CodeCheck {
+	svc := otp.NewService(otp.ServiceConfig{})
+	return func(stored otp.OTP) (bool, error) {
+		return svc.Verify(stored, businessID, phone, code, otp.Policy{})
+	}
+}
+
+func TestUsageReportService_FillsEmptyBuckets(t *testing.T) {
+	svc := service.NewUsageReportService(&memEvents{}, usage.NewService(usage.ServiceConfig{}))
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	got, err := svc.Report(context.Background(), "b1", from, from.AddDate(0, 0, 2), usage.GranularityDay)
+	if err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if len(got) != 3 || got[0].Sends != 0 || got[1].Sends != 4 || !got[2].Period.Equal(from.AddDate(0, 0, 2)) {
+		t.Fatalf("unexpected report: %#v", got)
+	}
+	if _, err := svc.Report(context.Background(), "b1", from, from.AddDate(2, 0, 0), usage.GranularityDay); !errors.Is(err, usage.ErrInvalidRange) {
+		t.Fatalf("expected too long a range to be rejected, got %v", err)
+	}
+}
+
+func TestMeteredOTPRepository_CachesQuota(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	store := &memStore{quota: 100}
+	repo := service.NewMeteredOTPRepository(&fakeOTPRepo{}, &memCounter{counts: map[usage.Event]int64{}}, store, &memEvents{},
+		usage.NewService(usage.ServiceConfig{Now: func() time.Time { return now }}), discardLogger())
+
+	o := otp.OTP{BusinessID: "b1", PhoneNumber: "+989123456789"}
+	for i := 0; i < 3; i++ {
+		if err := repo.Save(ctx, o); err != nil {
+			t.Fatalf("save %d: %v", i, err)
+		}
+	}
+	if store.quotaReads != 1 {
+		t.Fatalf("expected the quota to be read once, got %d reads", store.quotaReads)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if err := repo.Save(ctx, o); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if store.quotaReads != 2 {
+		t.Fatalf("expected the quota to be read again once the cache expired, got %d reads", store.quotaReads)
+	}
+}
+
+func TestUsageFlusher_RequeuesOnFailure(t *testing.T) {
+	ctx := context.Background()
+	day := usage.Daily{BusinessID: "b1", Day: usage.Day(time.Now()), Sends: 3}
+	counter := &memCounter{dirty: []usage.Daily{day}}
+	store := &memStore{failing: true}
+	flusher := service.NewUsageFlusher(counter, store, time.Minute, discardLogger())
+
+	if err := flusher.Flush(ctx); err == nil {
+		t.Fatal("expected flush to fail")
+	}
+	if len(counter.dirty) != 1 {
+		t.Fatalf("expected the day to be requeued, got %#v", counter.dirty)
+	}
+
+	store.failing = false
+	if err := flusher.Flush(ctx); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if len(store.flushed) != 1 || store.flushed[0] != day || len(counter.dirty) != 0 {
+		t.Fatalf("expected day to be flushed, got %#v (dirty %#v)", store.flushed, counter.dirty)
+	}
+}
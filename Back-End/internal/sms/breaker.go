@@ -0,0 +1,112 @@
+package sms
+
+import (
+	"sync"
+	"time"
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// CircuitBreaker stops traffic to a provider after FailureThreshold consecutive
+// failures. Once OpenTimeout has passed it lets a single probe through (half-open):
+// a successful probe closes the circuit, a failed one re-opens it.
+type CircuitBreaker struct {
+	mu sync.Mutex
+
+	failureThreshold int
+	openTimeout      time.Duration
+	now              func() time.Time
+
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+type CircuitBreakerConfig struct {
+	FailureThreshold int
+	OpenTimeout      time.Duration
+	Now              func() time.Time
+}
+
+func NewCircuitBreaker(cfg CircuitBreakerConfig) *CircuitBreaker {
+	now := cfg.Now
+	if now == nil {
+		now = time.Now
+	}
+	threshold := cfg.FailureThreshold
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &CircuitBreaker{
+		failureThreshold: threshold,
+		openTimeout:      cfg.OpenTimeout,
+		now:              now,
+	}
+}
+
+// Allow reports whether a call may go through. In half-open state only one probe is admitted at a time.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = breakerClosed
+	b.failures = 0
+	b.probing = false
+}
+
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.failureThreshold {
+		b.state = breakerOpen
+		b.openedAt = b.now()
+	}
+	b.probing = false
+}
+
+// Cancel releases a call Allow admitted that ended without telling anything about the
+// provider's health, such as one the caller cancelled. A half-open circuit stays
+// half-open and admits the next probe.
+func (b *CircuitBreaker) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// Open reports whether the circuit currently rejects calls.
+func (b *CircuitBreaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == breakerOpen && b.now().Sub(b.openedAt) < b.openTimeout
+}
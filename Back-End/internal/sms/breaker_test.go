@@ -0,0 +1,59 @@
+package sms_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/sms"
+)
+
+func TestCircuitBreaker_OpensAndProbes(t *testing.T) {
+	now := time.Unix(100, 0)
+	b := sms.NewCircuitBreaker(sms.CircuitBreakerConfig{
+		FailureThreshold: 2,
+		OpenTimeout:      30 * time.Second,
+		Now:              func() time.Time { return now },
+	})
+
+	b.Failure()
+	if !b.Allow() {
+		t.Fatalf("expected closed circuit below threshold")
+	}
+	b.Failure()
+	if b.Allow() || !b.Open() {
+		t.Fatalf("expected circuit to open after 2 consecutive failures")
+	}
+
+	now = now.Add(30 * time.Second)
+	if !b.Allow() {
+		t.Fatalf("expected a half-open probe after the open timeout")
+	}
+	if b.Allow() {
+		t.Fatalf("expected only one probe while half-open")
+	}
+
+	b.Failure()
+	if b.Allow() {
+		t.Fatalf("expected failed probe to re-open the circuit")
+	}
+
+	now = now.Add(30 * time.Second)
+	if !b.Allow() {
+		t.Fatalf("expected another probe")
+	}
+	b.Success()
+	if !b.Allow() || !b.Allow() || b.Open() {
+		t.Fatalf("expected successful probe to close the circuit")
+	}
+}
+
+func TestCircuitBreaker_SuccessResetsFailures(t *testing.T) {
+	b := sms.NewCircuitBreaker(sms.CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute})
+
+	b.Failure()
+	b.Success()
+	b.Failure()
+	if !b.Allow() {
+		t.Fatalf("expected failures to be counted consecutively")
+	}
+}
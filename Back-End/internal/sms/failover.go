@@ -0,0 +1,77 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+)
+
+var ErrNoProviderAvailable = errors.New("sms: no provider available")
+
+// Provider is a named sender guarded by its own circuit breaker.
+type Provider struct {
+	Name    string
+	Sender  Sender
+	Breaker *CircuitBreaker
+}
+
+// FailoverSender tries providers in priority order, skipping those whose circuit is
+// open, and falls back to the next one when a send fails.
+type FailoverSender struct {
+	providers []Provider
+	logger    *slog.Logger
+}
+
+func NewFailoverSender(logger *slog.Logger, providers ...Provider) *FailoverSender {
+	return &FailoverSender{providers: providers, logger: logger}
+}
+
+func (s *FailoverSender) Send(ctx context.Context, phone otp.PhoneNumber, code string) error {
+	_, err := s.Deliver(ctx, phone, code)
+	return err
+}
+
+// Deliver sends the code and returns a receipt naming the provider that accepted it,
+// so callers can record it for logging, billing and delivery reports.
+func (s *FailoverSender) Deliver(ctx context.Context, phone otp.PhoneNumber, code string) (Receipt, error) {
+	var errs []error
+	for _, p := range s.providers {
+		if !p.Breaker.Allow() {
+			continue
+		}
+
+		var messageID string
+		var err error
+		if ms, ok := p.Sender.(MessageSender); ok {
+			messageID, err = ms.SendMessage(ctx, phone, code)
+		} else {
+			err = p.Sender.Send(ctx, phone, code)
+		}
+		if err == nil {
+			p.Breaker.Success()
+			s.logger.InfoContext(ctx, "otp_delivered", slog.String("provider", p.Name), slog.String("message_id", messageID))
+			return Receipt{Provider: p.Name, MessageID: messageID}, nil
+		}
+		if ctx.Err() != nil {
+			// The caller gave up; that says nothing about the provider's health.
+			p.Breaker.Cancel()
+			return Receipt{}, ctx.Err()
+		}
+
+		p.Breaker.Failure()
+		s.logger.WarnContext(ctx, "otp_delivery_failed",
+			slog.String("provider", p.Name),
+			slog.Bool("circuit_open", p.Breaker.Open()),
+			slog.Any("err", err),
+		)
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name, err))
+	}
+
+	if len(errs) == 0 {
+		return Receipt{}, ErrNoProviderAvailable
+	}
+	return Receipt{}, errors.Join(errs...)
+}
@@ -0,0 +1,102 @@
+package sms_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+	"github.com/panbeh/otp-backend/internal/sms"
+)
+
+type fakeSender struct {
+	err   error
+	calls int
+}
+
+func (f *fakeSender) Send(ctx context.Context, phone otp.PhoneNumber, code string) error {
+	f.calls++
+	return f.err
+}
+
+func newProvider(name string, s sms.Sender) sms.Provider {
+	return sms.Provider{
+		Name:    name,
+		Sender:  s,
+		Breaker: sms.NewCircuitBreaker(sms.CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute}),
+	}
+}
+
+func TestFailoverSender_FallsBackAndSkipsOpenCircuits(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	primary := &fakeSender{err: sms.ErrDeliveryFailed}
+	secondary := &fakeSender{}
+	s := sms.NewFailoverSender(logger, newProvider("primary", primary), newProvider("secondary", secondary))
+
+	receipt, err := s.Deliver(context.Background(), "+989123456789", "123456")
+	if err != nil || receipt.Provider != "secondary" {
+		t.Fatalf("expected delivery via secondary, got %+v err=%v", receipt, err)
+	}
+
+	// Primary's circuit is now open, so it is not tried again.
+	receipt, err = s.Deliver(context.Background(), "+989123456789", "123456")
+	if err != nil || receipt.Provider != "secondary" {
+		t.Fatalf("expected delivery via secondary, got %+v err=%v", receipt, err)
+	}
+	if primary.calls != 1 || secondary.calls != 2 {
+		t.Fatalf("unexpected calls primary=%d secondary=%d", primary.calls, secondary.calls)
+	}
+}
+
+func TestFailoverSender_AllProvidersFail(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s := sms.NewFailoverSender(logger,
+		newProvider("a", &fakeSender{err: sms.ErrDeliveryFailed}),
+		newProvider("b", &fakeSender{err: sms.ErrDeliveryFailed}),
+	)
+
+	if err := s.Send(context.Background(), "+989123456789", "123456"); !errors.Is(err, sms.ErrDeliveryFailed) {
+		t.Fatalf("expected joined delivery errors, got %v", err)
+	}
+	if err := s.Send(context.Background(), "+989123456789", "123456"); !errors.Is(err, sms.ErrNoProviderAvailable) {
+		t.Fatalf("expected ErrNoProviderAvailable with all circuits open, got %v", err)
+	}
+}
+
+// cancellingSender cancels the caller's context mid-send, as a client hanging up would.
+type cancellingSender struct {
+	cancel context.CancelFunc
+	calls  int
+}
+
+func (c *cancellingSender) Send(ctx context.Context, phone otp.PhoneNumber, code string) error {
+	c.calls++
+	c.cancel()
+	return ctx.Err()
+}
+
+func TestFailoverSender_CancelledProbeReleasesHalfOpenCircuit(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	now := time.Unix(100, 0)
+	breaker := sms.NewCircuitBreaker(sms.CircuitBreakerConfig{
+		FailureThreshold: 1,
+		OpenTimeout:      time.Minute,
+		Now:              func() time.Time { return now },
+	})
+	breaker.Failure()
+	now = now.Add(time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sender := &cancellingSender{cancel: cancel}
+	s := sms.NewFailoverSender(logger, sms.Provider{Name: "primary", Sender: sender, Breaker: breaker})
+
+	if _, err := s.Deliver(ctx, "+989123456789", "123456"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if !breaker.Allow() {
+		t.Fatal("expected the cancelled probe to be released so the next call can probe")
+	}
+}
@@ -0,0 +1,98 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"text/template"
+
+	"github.com/panbeh/otp-backend/internal/config"
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+)
+
+// HTTPSender posts a templated request to any gateway that isn't worth a dedicated adapter.
+// URL and body templates get the fields of httpTemplateData; any 2xx response counts as sent.
+type HTTPSender struct {
+	client      *http.Client
+	method      string
+	url         *template.Template
+	body        *template.Template
+	headers     map[string]string
+	contentType string
+}
+
+// httpTemplateData is what the URL and body templates see. Phone and Code are raw;
+// PhoneQuery and CodeQuery are query-escaped for the URL, so "+98..." arrives as
+// "%2B98..." rather than a space. {{.Phone | urlquery}} is the same as {{.PhoneQuery}}.
+type httpTemplateData struct {
+	Phone      string
+	Code       string
+	PhoneQuery string
+	CodeQuery  string
+}
+
+func NewHTTPSender(client *http.Client, cfg config.SMSHTTP) (*HTTPSender, error) {
+	urlTmpl, err := template.New("url").Option("missingkey=error").Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("sms: invalid url template: %w", err)
+	}
+	bodyTmpl, err := template.New("body").Option("missingkey=error").Parse(cfg.BodyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("sms: invalid body template: %w", err)
+	}
+	method := cfg.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	return &HTTPSender{
+		client:      client,
+		method:      method,
+		url:         urlTmpl,
+		body:        bodyTmpl,
+		headers:     cfg.Headers,
+		contentType: cfg.ContentType,
+	}, nil
+}
+
+func (s *HTTPSender) Send(ctx context.Context, phone otp.PhoneNumber, code string) error {
+	data := httpTemplateData{
+		Phone:      string(phone),
+		Code:       code,
+		PhoneQuery: url.QueryEscape(string(phone)),
+		CodeQuery:  url.QueryEscape(code),
+	}
+
+	var u, body bytes.Buffer
+	if err := s.url.Execute(&u, data); err != nil {
+		return err
+	}
+	if err := s.body.Execute(&body, data); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, s.method, u.String(), &body)
+	if err != nil {
+		return err
+	}
+	if s.contentType != "" {
+		req.Header.Set("Content-Type", s.contentType)
+	}
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: http: %v", ErrDeliveryFailed, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("%w: http: status %d: %s", ErrDeliveryFailed, res.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
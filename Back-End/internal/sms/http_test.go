@@ -0,0 +1,102 @@
+package sms_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/panbeh/otp-backend/internal/config"
+	"github.com/panbeh/otp-backend/internal/sms"
+)
+
+func TestHTTPSender_Send(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Query().Get("to") != "+989123456789" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		if r.Header.Get("Authorization") != "Bearer abc" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+		b, _ := io.ReadAll(r.Body)
+		if string(b) != `{"text":"Your code is 123456"}` {
+			t.Errorf("unexpected body %s", b)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	s, err := sms.NewHTTPSender(srv.Client(), config.SMSHTTP{
+		URL:          srv.URL + "/send?to={{.Phone | urlquery}}",
+		Method:       http.MethodPut,
+		Headers:      map[string]string{"Authorization": "Bearer abc"},
+		ContentType:  "application/json",
+		BodyTemplate: `{"text":"Your code is {{.Code}}"}`,
+	})
+	if err != nil {
+		t.Fatalf("new sender: %v", err)
+	}
+	if err := s.Send(context.Background(), "+989123456789", "123456"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestHTTPSender_Send_EscapesURLValues(t *testing.T) {
+	for _, tmpl := range []string{"/send?to={{.PhoneQuery}}&text={{.CodeQuery}}", "/send?to={{.Phone | urlquery}}&text={{.Code | urlquery}}"} {
+		var gotTo, gotText string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			gotTo, gotText = r.URL.Query().Get("to"), r.URL.Query().Get("text")
+		}))
+
+		s, err := sms.NewHTTPSender(srv.Client(), config.SMSHTTP{URL: srv.URL + tmpl, BodyTemplate: "{{.Code}}"})
+		if err != nil {
+			t.Fatalf("new sender: %v", err)
+		}
+		if err := s.Send(context.Background(), "+989123456789", "a&b=c"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		srv.Close()
+
+		if gotTo != "+989123456789" || gotText != "a&b=c" {
+			t.Errorf("%s: expected values to arrive intact, got to=%q text=%q", tmpl, gotTo, gotText)
+		}
+	}
+}
+
+func TestHTTPSender_Send_Non2xx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	s, err := sms.NewHTTPSender(srv.Client(), config.SMSHTTP{URL: srv.URL, BodyTemplate: "{{.Code}}"})
+	if err != nil {
+		t.Fatalf("new sender: %v", err)
+	}
+	if err := s.Send(context.Background(), "+989123456789", "123456"); !errors.Is(err, sms.ErrDeliveryFailed) {
+		t.Fatalf("expected ErrDeliveryFailed, got %v", err)
+	}
+}
+
+func TestNewSender_SelectsProvider(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s, err := sms.NewSender(config.SMS{
+		Providers: []config.SMSProvider{config.SMSProviderLog, config.SMSProviderKavenegar},
+		Timeout:   time.Second,
+	}, logger)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	receipt, err := s.Deliver(context.Background(), "+989123456789", "123456")
+	if err != nil || receipt.Provider != "log" {
+		t.Fatalf("expected log provider to deliver first, got %+v err=%v", receipt, err)
+	}
+
+	if _, err := sms.NewSender(config.SMS{Providers: []config.SMSProvider{"carrier-pigeon"}, Timeout: time.Second}, nil); err == nil {
+		t.Fatalf("expected error for unknown provider")
+	}
+}
@@ -0,0 +1,84 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/panbeh/otp-backend/internal/config"
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+)
+
+const kavenegarDefaultBaseURL = "https://api.kavenegar.com"
+
+// KavenegarSender sends codes through Kavenegar's verify/lookup (template) API.
+type KavenegarSender struct {
+	client   *http.Client
+	baseURL  string
+	apiKey   string
+	template string
+}
+
+func NewKavenegarSender(client *http.Client, cfg config.Kavenegar) *KavenegarSender {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = kavenegarDefaultBaseURL
+	}
+	return &KavenegarSender{
+		client:   client,
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		apiKey:   cfg.APIKey,
+		template: cfg.Template,
+	}
+}
+
+type kavenegarResponse struct {
+	Return struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	} `json:"return"`
+	Entries []struct {
+		MessageID int64 `json:"messageid"`
+	} `json:"entries"`
+}
+
+func (s *KavenegarSender) Send(ctx context.Context, phone otp.PhoneNumber, code string) error {
+	_, err := s.SendMessage(ctx, phone, code)
+	return err
+}
+
+func (s *KavenegarSender) SendMessage(ctx context.Context, phone otp.PhoneNumber, code string) (string, error) {
+	form := url.Values{}
+	form.Set("receptor", gatewayNumber(phone))
+	form.Set("token", code)
+	form.Set("template", s.template)
+
+	endpoint := fmt.Sprintf("%s/v1/%s/verify/lookup.json", s.baseURL, url.PathEscape(s.apiKey))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: kavenegar: %v", ErrDeliveryFailed, err)
+	}
+	defer res.Body.Close()
+
+	var body kavenegarResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("%w: kavenegar: http %d: invalid response: %v", ErrDeliveryFailed, res.StatusCode, err)
+	}
+	if res.StatusCode != http.StatusOK || body.Return.Status != http.StatusOK {
+		return "", fmt.Errorf("%w: kavenegar: status %d: %s", ErrDeliveryFailed, body.Return.Status, body.Return.Message)
+	}
+	if len(body.Entries) == 0 {
+		return "", nil
+	}
+	return strconv.FormatInt(body.Entries[0].MessageID, 10), nil
+}
@@ -0,0 +1,66 @@
+package sms_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/panbeh/otp-backend/internal/config"
+	"github.com/panbeh/otp-backend/internal/sms"
+)
+
+func TestKavenegarSender_Send(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/key1/verify/lookup.json" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("parse form: %v", err)
+		}
+		if r.PostForm.Get("receptor") != "09123456789" || r.PostForm.Get("token") != "123456" || r.PostForm.Get("template") != "login" {
+			t.Errorf("unexpected form: %v", r.PostForm)
+		}
+		_, _ = w.Write([]byte(`{"return":{"status":200,"message":"ok"},"entries":[{"messageid":8792343,"status":5}]}`))
+	}))
+	defer srv.Close()
+
+	s := sms.NewKavenegarSender(srv.Client(), config.Kavenegar{BaseURL: srv.URL, APIKey: "key1", Template: "login"})
+	messageID, err := s.SendMessage(context.Background(), "+989123456789", "123456")
+	if err != nil || messageID != "8792343" {
+		t.Fatalf("expected message id 8792343, got %q err=%v", messageID, err)
+	}
+}
+
+func TestKavenegarSender_Send_InternationalNumber(t *testing.T) {
+	var receptor string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		receptor = r.PostForm.Get("receptor")
+		_, _ = w.Write([]byte(`{"return":{"status":200,"message":"ok"},"entries":[{"messageid":1,"status":5}]}`))
+	}))
+	defer srv.Close()
+
+	s := sms.NewKavenegarSender(srv.Client(), config.Kavenegar{BaseURL: srv.URL, APIKey: "key1", Template: "login"})
+	if err := s.Send(context.Background(), "+971501234567", "123456"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if receptor != "00971501234567" {
+		t.Fatalf("expected the international form for a non-Iranian number, got %q", receptor)
+	}
+}
+
+func TestKavenegarSender_Send_ProviderError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"return":{"status":403,"message":"invalid api key"},"entries":null}`))
+	}))
+	defer srv.Close()
+
+	s := sms.NewKavenegarSender(srv.Client(), config.Kavenegar{BaseURL: srv.URL, APIKey: "bad", Template: "login"})
+	err := s.Send(context.Background(), "+989123456789", "123456")
+	if !errors.Is(err, sms.ErrDeliveryFailed) {
+		t.Fatalf("expected ErrDeliveryFailed, got %v", err)
+	}
+}
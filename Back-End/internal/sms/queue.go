@@ -0,0 +1,57 @@
+package sms
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+)
+
+const (
+	deliveryStream = "otp:delivery"
+	// The retry set and dead-letter stream are tagged with the stream's name, so they
+	// hash to the stream's cluster slot and can share scripts and transactions with it.
+	deliveryRetrySet    = "{otp:delivery}:retry"
+	deliveryDeadStream  = "{otp:delivery}:dead"
+	deliveryGroup       = "otp-senders"
+	deliveryStreamLimit = 100_000
+)
+
+// DeliveryQueue is a Sender that only enqueues the delivery on a Redis stream;
+// a Worker picks it up and talks to the SMS gateway outside the request path.
+type DeliveryQueue struct {
+	client  redis.UniversalClient
+	sealer  CodeSealer
+	tracker Tracker
+	logger  *slog.Logger
+}
+
+// NewDeliveryQueue builds the queue; tracker may be nil to skip delivery tracking.
+func NewDeliveryQueue(client redis.UniversalClient, sealer CodeSealer, tracker Tracker, logger *slog.Logger) *DeliveryQueue {
+	return &DeliveryQueue{client: client, sealer: sealer, tracker: tracker, logger: logger}
+}
+
+func (q *DeliveryQueue) Send(ctx context.Context, phone otp.PhoneNumber, code string) error {
+	sealed, err := q.sealer.Seal(code)
+	if err != nil {
+		return err
+	}
+
+	job := deliveryJob{Phone: string(phone), SealedCode: sealed, Attempt: 1}
+	if req, ok := RequestFromContext(ctx); ok && q.tracker != nil {
+		id, err := q.tracker.Queued(ctx, req, phone)
+		if err != nil {
+			q.logger.ErrorContext(ctx, "delivery_track_failed", slog.String("request_id", req.ID), slog.Any("err", err))
+		}
+		job.RequestID = id
+	}
+
+	return q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: deliveryStream,
+		MaxLen: deliveryStreamLimit,
+		Approx: true,
+		Values: job.values(),
+	}).Err()
+}
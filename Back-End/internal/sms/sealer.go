@@ -0,0 +1,53 @@
+package sms
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+var ErrInvalidSealedCode = errors.New("sms: invalid sealed code")
+
+// CodeSealer encrypts codes that have to sit in the delivery queue, so queued jobs
+// don't reintroduce plaintext OTPs into Redis.
+type CodeSealer struct {
+	aead cipher.AEAD
+}
+
+func NewCodeSealer(secret []byte) (CodeSealer, error) {
+	// Derive a dedicated key so the queue never shares key material with the code HMAC.
+	key := sha256.Sum256(append([]byte("otp-delivery:"), secret...))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return CodeSealer{}, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return CodeSealer{}, err
+	}
+	return CodeSealer{aead: aead}, nil
+}
+
+func (s CodeSealer) Seal(code string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawStdEncoding.EncodeToString(s.aead.Seal(nonce, nonce, []byte(code), nil)), nil
+}
+
+func (s CodeSealer) Open(sealed string) (string, error) {
+	b, err := base64.RawStdEncoding.DecodeString(sealed)
+	if err != nil || len(b) < s.aead.NonceSize() {
+		return "", ErrInvalidSealedCode
+	}
+	nonce, ciphertext := b[:s.aead.NonceSize()], b[s.aead.NonceSize():]
+	code, err := s.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrInvalidSealedCode
+	}
+	return string(code), nil
+}
@@ -0,0 +1,103 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/panbeh/otp-backend/internal/config"
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+)
+
+var ErrDeliveryFailed = errors.New("sms: delivery failed")
+
+// Sender delivers an OTP code to a phone number through an SMS gateway.
+type Sender interface {
+	Send(ctx context.Context, phone otp.PhoneNumber, code string) error
+}
+
+// MessageSender is implemented by gateways that return an ID for the accepted message,
+// which their delivery reports refer back to.
+type MessageSender interface {
+	SendMessage(ctx context.Context, phone otp.PhoneNumber, code string) (messageID string, err error)
+}
+
+// Deliverer is a Sender that can tell which provider accepted the message.
+type Deliverer interface {
+	Deliver(ctx context.Context, phone otp.PhoneNumber, code string) (Receipt, error)
+}
+
+// Receipt identifies who accepted a message and under which provider-side ID.
+type Receipt struct {
+	Provider  string
+	MessageID string
+}
+
+// NewSender builds the sender for the configured providers, so the same binary can
+// just log codes in dev and hit a real gateway in prod. Providers are tried in the
+// configured order, each behind its own circuit breaker.
+func NewSender(cfg config.SMS, logger *slog.Logger) (*FailoverSender, error) {
+	client := &http.Client{Timeout: cfg.Timeout}
+
+	providers := make([]Provider, 0, len(cfg.Providers))
+	for _, name := range cfg.Providers {
+		sender, err := newProviderSender(name, client, cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, Provider{
+			Name:   string(name),
+			Sender: sender,
+			Breaker: NewCircuitBreaker(CircuitBreakerConfig{
+				FailureThreshold: cfg.BreakerFailureThreshold,
+				OpenTimeout:      cfg.BreakerOpenTimeout,
+			}),
+		})
+	}
+
+	if len(providers) == 0 {
+		return nil, errors.New("sms: no provider configured")
+	}
+	return NewFailoverSender(logger, providers...), nil
+}
+
+func newProviderSender(provider config.SMSProvider, client *http.Client, cfg config.SMS, logger *slog.Logger) (Sender, error) {
+	switch provider {
+	case config.SMSProviderLog:
+		return NewLogSender(logger), nil
+	case config.SMSProviderKavenegar:
+		return NewKavenegarSender(client, cfg.Kavenegar), nil
+	case config.SMSProviderSMSIR:
+		return NewSMSIRSender(client, cfg.SMSIR), nil
+	case config.SMSProviderHTTP:
+		return NewHTTPSender(client, cfg.HTTP)
+	}
+	return nil, fmt.Errorf("sms: unknown provider %q", provider)
+}
+
+// gatewayNumber is how the Iranian gateways want a recipient: Iranian numbers in the
+// national form (09…), every other country in the international 00-prefixed form,
+// since a national number alone doesn't say which country it is in.
+func gatewayNumber(phone otp.PhoneNumber) string {
+	if phone.HasCallingCode("98") {
+		return phone.National()
+	}
+	return "00" + strings.TrimPrefix(string(phone), "+")
+}
+
+type LogSender struct {
+	logger *slog.Logger
+}
+
+// NewLogSender returns a sender that only logs the code. Meant for local development.
+func NewLogSender(logger *slog.Logger) *LogSender {
+	return &LogSender{logger: logger}
+}
+
+func (s *LogSender) Send(ctx context.Context, phone otp.PhoneNumber, code string) error {
+	s.logger.InfoContext(ctx, "otp_send", slog.String("phone", string(phone)), slog.String("code", code))
+	return nil
+}
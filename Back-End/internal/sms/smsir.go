@@ -0,0 +1,99 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/panbeh/otp-backend/internal/config"
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+)
+
+const smsIRDefaultBaseURL = "https://api.sms.ir"
+
+// SMSIRSender sends codes through SMS.ir's template (verify) API. Ghasedak and similar
+// gateways use the same API-key header + JSON template shape.
+type SMSIRSender struct {
+	client     *http.Client
+	baseURL    string
+	apiKey     string
+	templateID int
+	codeParam  string
+}
+
+func NewSMSIRSender(client *http.Client, cfg config.SMSIR) *SMSIRSender {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = smsIRDefaultBaseURL
+	}
+	return &SMSIRSender{
+		client:     client,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		apiKey:     cfg.APIKey,
+		templateID: cfg.TemplateID,
+		codeParam:  cfg.CodeParam,
+	}
+}
+
+type smsIRParameter struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type smsIRRequest struct {
+	Mobile     string           `json:"mobile"`
+	TemplateID int              `json:"templateId"`
+	Parameters []smsIRParameter `json:"parameters"`
+}
+
+type smsIRResponse struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+	Data    struct {
+		MessageID int64 `json:"messageId"`
+	} `json:"data"`
+}
+
+func (s *SMSIRSender) Send(ctx context.Context, phone otp.PhoneNumber, code string) error {
+	_, err := s.SendMessage(ctx, phone, code)
+	return err
+}
+
+func (s *SMSIRSender) SendMessage(ctx context.Context, phone otp.PhoneNumber, code string) (string, error) {
+	payload, err := json.Marshal(smsIRRequest{
+		Mobile:     gatewayNumber(phone),
+		TemplateID: s.templateID,
+		Parameters: []smsIRParameter{{Name: s.codeParam, Value: code}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/v1/send/verify", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-API-KEY", s.apiKey)
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: smsir: %v", ErrDeliveryFailed, err)
+	}
+	defer res.Body.Close()
+
+	var body smsIRResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("%w: smsir: http %d: invalid response: %v", ErrDeliveryFailed, res.StatusCode, err)
+	}
+	// SMS.ir reports success with status 1.
+	if res.StatusCode != http.StatusOK || body.Status != 1 {
+		return "", fmt.Errorf("%w: smsir: status %d: %s", ErrDeliveryFailed, body.Status, body.Message)
+	}
+	return strconv.FormatInt(body.Data.MessageID, 10), nil
+}
@@ -0,0 +1,77 @@
+package sms_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/panbeh/otp-backend/internal/config"
+	"github.com/panbeh/otp-backend/internal/sms"
+)
+
+func TestSMSIRSender_Send(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/send/verify" || r.Header.Get("X-API-KEY") != "key1" {
+			t.Errorf("unexpected request %s %v", r.URL.Path, r.Header)
+		}
+		var body struct {
+			Mobile     string `json:"mobile"`
+			TemplateID int    `json:"templateId"`
+			Parameters []struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"parameters"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if body.Mobile != "09123456789" || body.TemplateID != 42 || len(body.Parameters) != 1 ||
+			body.Parameters[0].Name != "CODE" || body.Parameters[0].Value != "123456" {
+			t.Errorf("unexpected body: %+v", body)
+		}
+		_, _ = w.Write([]byte(`{"status":1,"message":"ok","data":{"messageId":77,"cost":1}}`))
+	}))
+	defer srv.Close()
+
+	s := sms.NewSMSIRSender(srv.Client(), config.SMSIR{BaseURL: srv.URL, APIKey: "key1", TemplateID: 42, CodeParam: "CODE"})
+	messageID, err := s.SendMessage(context.Background(), "+989123456789", "123456")
+	if err != nil || messageID != "77" {
+		t.Fatalf("expected message id 77, got %q err=%v", messageID, err)
+	}
+}
+
+func TestSMSIRSender_Send_ProviderError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"status":0,"message":"template not found"}`))
+	}))
+	defer srv.Close()
+
+	s := sms.NewSMSIRSender(srv.Client(), config.SMSIR{BaseURL: srv.URL, APIKey: "key1", TemplateID: 42, CodeParam: "CODE"})
+	if err := s.Send(context.Background(), "+989123456789", "123456"); !errors.Is(err, sms.ErrDeliveryFailed) {
+		t.Fatalf("expected ErrDeliveryFailed, got %v", err)
+	}
+}
+
+func TestSMSIRSender_Send_InternationalNumber(t *testing.T) {
+	var mobile string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Mobile string `json:"mobile"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mobile = body.Mobile
+		_, _ = w.Write([]byte(`{"status":1,"message":"ok","data":{"messageId":1,"cost":1}}`))
+	}))
+	defer srv.Close()
+
+	s := sms.NewSMSIRSender(srv.Client(), config.SMSIR{BaseURL: srv.URL, APIKey: "key1", TemplateID: 42, CodeParam: "CODE"})
+	if err := s.Send(context.Background(), "+905321234567", "123456"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mobile != "00905321234567" {
+		t.Fatalf("expected the international form for a non-Iranian number, got %q", mobile)
+	}
+}
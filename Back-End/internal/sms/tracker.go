@@ -0,0 +1,130 @@
+package sms
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/panbeh/otp-backend/internal/domain/delivery"
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+)
+
+// Request identifies the OTP send request a delivery belongs to.
+type Request struct {
+	ID         string
+	BusinessID string
+}
+
+type requestKey struct{}
+
+// WithRequest attaches the request to ctx so senders can track its delivery.
+// Sends without a request still go out, they just aren't tracked.
+func WithRequest(ctx context.Context, req Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, req)
+}
+
+func RequestFromContext(ctx context.Context) (Request, bool) {
+	req, ok := ctx.Value(requestKey{}).(Request)
+	return req, ok && req.BusinessID != ""
+}
+
+// Tracker records delivery progress for tracked requests.
+type Tracker interface {
+	Queued(ctx context.Context, req Request, phone otp.PhoneNumber) (string, error)
+	Sent(ctx context.Context, id string, receipt Receipt) error
+	Failed(ctx context.Context, id string, reason string) error
+}
+
+// DeliveryTracker persists delivery records through the delivery domain.
+type DeliveryTracker struct {
+	repo delivery.Repository
+	svc  *delivery.Service
+}
+
+func NewDeliveryTracker(repo delivery.Repository, svc *delivery.Service) *DeliveryTracker {
+	return &DeliveryTracker{repo: repo, svc: svc}
+}
+
+func (t *DeliveryTracker) Queued(ctx context.Context, req Request, phone otp.PhoneNumber) (string, error) {
+	d, err := t.svc.NewDelivery(req.ID, req.BusinessID, string(phone))
+	if err != nil {
+		return "", err
+	}
+	if err := t.repo.Create(ctx, d); err != nil {
+		return "", err
+	}
+	return d.ID, nil
+}
+
+func (t *DeliveryTracker) Sent(ctx context.Context, id string, receipt Receipt) error {
+	return t.update(ctx, id, func(d delivery.Delivery) (delivery.Delivery, error) {
+		return t.svc.MarkSent(d, receipt.Provider, receipt.MessageID)
+	})
+}
+
+func (t *DeliveryTracker) Failed(ctx context.Context, id string, reason string) error {
+	return t.update(ctx, id, func(d delivery.Delivery) (delivery.Delivery, error) {
+		return t.svc.MarkFailed(d, reason)
+	})
+}
+
+func (t *DeliveryTracker) Report(ctx context.Context, provider, messageID string, delivered bool, reason string) error {
+	d, err := t.repo.GetByProviderMessageID(ctx, provider, messageID)
+	if err != nil {
+		return err
+	}
+	from := d.Status
+	d, err = t.svc.ApplyReport(d, delivered, reason)
+	if err != nil {
+		return err
+	}
+	return t.repo.Update(ctx, d, from)
+}
+
+func (t *DeliveryTracker) update(ctx context.Context, id string, apply func(delivery.Delivery) (delivery.Delivery, error)) error {
+	d, err := t.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	from := d.Status
+	d, err = apply(d)
+	if err != nil {
+		return err
+	}
+	return t.repo.Update(ctx, d, from)
+}
+
+// TrackingSender records a delivery for every tracked request it sends synchronously.
+// Tracking failures are logged, never surfaced: a missing status row must not block a login.
+type TrackingSender struct {
+	sender  *FailoverSender
+	tracker Tracker
+	logger  *slog.Logger
+}
+
+func NewTrackingSender(sender *FailoverSender, tracker Tracker, logger *slog.Logger) *TrackingSender {
+	return &TrackingSender{sender: sender, tracker: tracker, logger: logger}
+}
+
+func (s *TrackingSender) Send(ctx context.Context, phone otp.PhoneNumber, code string) error {
+	req, ok := RequestFromContext(ctx)
+	if !ok {
+		return s.sender.Send(ctx, phone, code)
+	}
+
+	id, err := s.tracker.Queued(ctx, req, phone)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "delivery_track_failed", slog.String("request_id", req.ID), slog.Any("err", err))
+		return s.sender.Send(ctx, phone, code)
+	}
+
+	receipt, sendErr := s.sender.Deliver(ctx, phone, code)
+	if sendErr != nil {
+		err = s.tracker.Failed(ctx, id, sendErr.Error())
+	} else {
+		err = s.tracker.Sent(ctx, id, receipt)
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "delivery_track_failed", slog.String("request_id", id), slog.Any("err", err))
+	}
+	return sendErr
+}
@@ -0,0 +1,323 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+)
+
+type deliveryJob struct {
+	ID         string `json:"id,omitempty"`
+	RequestID  string `json:"request_id"`
+	Phone      string `json:"phone"`
+	SealedCode string `json:"code"`
+	Attempt    int    `json:"attempt"`
+}
+
+func (j deliveryJob) values() map[string]any {
+	return map[string]any{
+		"request_id": j.RequestID,
+		"phone":      j.Phone,
+		"code":       j.SealedCode,
+		"attempt":    j.Attempt,
+	}
+}
+
+func parseDeliveryJob(msg redis.XMessage) (deliveryJob, error) {
+	phone, _ := msg.Values["phone"].(string)
+	code, _ := msg.Values["code"].(string)
+	attemptRaw, _ := msg.Values["attempt"].(string)
+	attempt, err := strconv.Atoi(attemptRaw)
+	if err != nil || phone == "" || code == "" || attempt <= 0 {
+		return deliveryJob{}, errors.New("sms: malformed delivery job " + msg.ID)
+	}
+	requestID, _ := msg.Values["request_id"].(string)
+	return deliveryJob{ID: msg.ID, RequestID: requestID, Phone: phone, SealedCode: code, Attempt: attempt}, nil
+}
+
+type WorkerConfig struct {
+	// Consumer identifies this process inside the consumer group; it must be unique per pod.
+	Consumer    string
+	Concurrency int
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// ClaimIdle is how long a job may stay unacknowledged before another consumer takes it over.
+	ClaimIdle time.Duration
+	// DrainTimeout bounds how long in-flight sends may run after shutdown starts.
+	DrainTimeout time.Duration
+	// Block is how long a read waits for new jobs; it also paces retry promotion.
+	Block time.Duration
+	// Tracker, if set, records sent/failed outcomes for tracked jobs.
+	Tracker Tracker
+	Now     func() time.Time
+}
+
+// Worker consumes the delivery stream with a consumer group and hands jobs to the
+// real sender. Failed sends are retried with exponential backoff through a delayed
+// sorted set and dead-lettered once MaxAttempts is reached.
+type Worker struct {
+	client redis.UniversalClient
+	sender Sender
+	sealer CodeSealer
+	logger *slog.Logger
+	cfg    WorkerConfig
+}
+
+func NewWorker(client redis.UniversalClient, sender Sender, sealer CodeSealer, logger *slog.Logger, cfg WorkerConfig) *Worker {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.Block <= 0 {
+		cfg.Block = time.Second
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &Worker{client: client, sender: sender, sealer: sealer, logger: logger, cfg: cfg}
+}
+
+// Run blocks until ctx is cancelled. Jobs already read from the stream are still
+// delivered (bounded by DrainTimeout) before Run returns.
+func (w *Worker) Run(ctx context.Context) error {
+	err := w.client.XGroupCreateMkStream(ctx, deliveryStream, deliveryGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+
+	drainCtx, cancelDrain := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelDrain()
+
+	jobs := make(chan redis.XMessage)
+	var wg sync.WaitGroup
+	for i := 0; i < w.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range jobs {
+				w.process(drainCtx, msg)
+			}
+		}()
+	}
+
+	for ctx.Err() == nil {
+		msgs, err := w.poll(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				w.logger.Error("delivery_poll_failed", slog.Any("err", err))
+				sleep(ctx, time.Second)
+			}
+			continue
+		}
+		for _, msg := range msgs {
+			jobs <- msg
+		}
+	}
+	close(jobs)
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	if w.cfg.DrainTimeout > 0 {
+		select {
+		case <-done:
+		case <-time.After(w.cfg.DrainTimeout):
+			// Unacknowledged jobs stay pending and are reclaimed by another consumer.
+			cancelDrain()
+			<-done
+		}
+	} else {
+		<-done
+	}
+	return nil
+}
+
+// poll promotes due retries, reclaims jobs abandoned by dead consumers and reads new ones.
+func (w *Worker) poll(ctx context.Context) ([]redis.XMessage, error) {
+	if err := w.promoteRetries(ctx); err != nil {
+		return nil, err
+	}
+
+	if w.cfg.ClaimIdle > 0 {
+		claimed, _, err := w.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   deliveryStream,
+			Group:    deliveryGroup,
+			Consumer: w.cfg.Consumer,
+			MinIdle:  w.cfg.ClaimIdle,
+			Start:    "0-0",
+			Count:    int64(w.cfg.Concurrency),
+		}).Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(claimed) > 0 {
+			return claimed, nil
+		}
+	}
+
+	streams, err := w.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    deliveryGroup,
+		Consumer: w.cfg.Consumer,
+		Streams:  []string{deliveryStream, ">"},
+		Count:    int64(w.cfg.Concurrency),
+		Block:    w.cfg.Block,
+	}).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var msgs []redis.XMessage
+	for _, s := range streams {
+		msgs = append(msgs, s.Messages...)
+	}
+	return msgs, nil
+}
+
+func (w *Worker) promoteRetries(ctx context.Context) error {
+	// Moves due jobs from the delayed set back onto the stream in one step so a job
+	// is never lost or duplicated between the two.
+	const script = `
+local due = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, 100)
+for _, member in ipairs(due) do
+  local job = cjson.decode(member)
+  redis.call("XADD", KEYS[2], "*", "request_id", job["request_id"] or "", "phone", job["phone"], "code", job["code"], "attempt", job["attempt"])
+  redis.call("ZREM", KEYS[1], member)
+end
+return #due
+`
+	return w.client.Eval(ctx, script, []string{deliveryRetrySet, deliveryStream}, w.cfg.Now().UnixMilli()).Err()
+}
+
+func (w *Worker) process(ctx context.Context, msg redis.XMessage) {
+	job, err := parseDeliveryJob(msg)
+	if err != nil {
+		w.logger.Error("delivery_job_invalid", slog.String("id", msg.ID), slog.Any("err", err))
+		w.deadLetter(ctx, deliveryJob{ID: msg.ID}, err)
+		return
+	}
+
+	var receipt Receipt
+	code, err := w.sealer.Open(job.SealedCode)
+	if err == nil {
+		receipt, err = w.deliver(ctx, otp.PhoneNumber(job.Phone), code)
+	}
+	if err == nil {
+		if err := w.ack(ctx, job.ID); err != nil {
+			w.logger.Error("delivery_ack_failed", slog.String("id", job.ID), slog.Any("err", err))
+		}
+		w.track(ctx, job, func(id string) error { return w.cfg.Tracker.Sent(ctx, id, receipt) })
+		return
+	}
+
+	if job.Attempt >= w.cfg.MaxAttempts || errors.Is(err, ErrInvalidSealedCode) {
+		w.logger.Error("delivery_dead_lettered", slog.String("id", job.ID), slog.Int("attempt", job.Attempt), slog.Any("err", err))
+		w.deadLetter(ctx, job, err)
+		w.track(ctx, job, func(id string) error { return w.cfg.Tracker.Failed(ctx, id, err.Error()) })
+		return
+	}
+
+	backoff := w.backoff(job.Attempt)
+	w.logger.Warn("delivery_retry_scheduled",
+		slog.String("id", job.ID),
+		slog.Int("attempt", job.Attempt),
+		slog.Duration("backoff", backoff),
+		slog.Any("err", err),
+	)
+	if err := w.retry(ctx, job, backoff); err != nil {
+		w.logger.Error("delivery_retry_failed", slog.String("id", job.ID), slog.Any("err", err))
+	}
+}
+
+func (w *Worker) deliver(ctx context.Context, phone otp.PhoneNumber, code string) (Receipt, error) {
+	if d, ok := w.sender.(Deliverer); ok {
+		return d.Deliver(ctx, phone, code)
+	}
+	return Receipt{}, w.sender.Send(ctx, phone, code)
+}
+
+func (w *Worker) track(ctx context.Context, job deliveryJob, record func(id string) error) {
+	if w.cfg.Tracker == nil || job.RequestID == "" {
+		return
+	}
+	if err := record(job.RequestID); err != nil {
+		w.logger.Error("delivery_track_failed", slog.String("request_id", job.RequestID), slog.Any("err", err))
+	}
+}
+
+func (w *Worker) backoff(attempt int) time.Duration {
+	d := w.cfg.BaseBackoff
+	for i := 1; i < attempt && d < w.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	if w.cfg.MaxBackoff > 0 && d > w.cfg.MaxBackoff {
+		d = w.cfg.MaxBackoff
+	}
+	return d
+}
+
+func (w *Worker) ack(ctx context.Context, id string) error {
+	pipe := w.client.TxPipeline()
+	pipe.XAck(ctx, deliveryStream, deliveryGroup, id)
+	pipe.XDel(ctx, deliveryStream, id)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (w *Worker) retry(ctx context.Context, job deliveryJob, backoff time.Duration) error {
+	next := job
+	next.Attempt++
+	member, err := json.Marshal(next)
+	if err != nil {
+		return err
+	}
+
+	pipe := w.client.TxPipeline()
+	pipe.ZAdd(ctx, deliveryRetrySet, redis.Z{
+		Score:  float64(w.cfg.Now().Add(backoff).UnixMilli()),
+		Member: member,
+	})
+	pipe.XAck(ctx, deliveryStream, deliveryGroup, job.ID)
+	pipe.XDel(ctx, deliveryStream, job.ID)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (w *Worker) deadLetter(ctx context.Context, job deliveryJob, cause error) {
+	values := job.values()
+	values["source_id"] = job.ID
+	values["error"] = cause.Error()
+
+	pipe := w.client.TxPipeline()
+	pipe.XAdd(ctx, &redis.XAddArgs{Stream: deliveryDeadStream, Values: values})
+	pipe.XAck(ctx, deliveryStream, deliveryGroup, job.ID)
+	pipe.XDel(ctx, deliveryStream, job.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		w.logger.Error("delivery_dead_letter_failed", slog.String("id", job.ID), slog.Any("err", err))
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
@@ -0,0 +1,145 @@
+package sms_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+	"github.com/panbeh/otp-backend/internal/sms"
+)
+
+type recordingSender struct {
+	mu       sync.Mutex
+	failures int
+	codes    []string
+}
+
+func (r *recordingSender) Send(ctx context.Context, phone otp.PhoneNumber, code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codes = append(r.codes, code)
+	if len(r.codes) <= r.failures {
+		return sms.ErrDeliveryFailed
+	}
+	return nil
+}
+
+func (r *recordingSender) calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.codes)
+}
+
+func runWorker(t *testing.T, sender sms.Sender, maxAttempts int) (*sms.DeliveryQueue, *redis.Client, func()) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	sealer, err := sms.NewCodeSealer([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("sealer: %v", err)
+	}
+	w := sms.NewWorker(client, sender, sealer, slog.New(slog.NewTextHandler(io.Discard, nil)), sms.WorkerConfig{
+		Consumer:    "test",
+		Concurrency: 2,
+		MaxAttempts: maxAttempts,
+		BaseBackoff: time.Millisecond,
+		MaxBackoff:  5 * time.Millisecond,
+		Block:       20 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	stop := func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("worker run: %v", err)
+		}
+	}
+	return sms.NewDeliveryQueue(client, sealer, nil, nil), client, stop
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met before deadline")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWorker_RetriesThenDelivers(t *testing.T) {
+	sender := &recordingSender{failures: 2}
+	queue, client, stop := runWorker(t, sender, 5)
+	defer stop()
+
+	if err := queue.Send(context.Background(), "+989123456789", "123456"); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	waitFor(t, func() bool { return sender.calls() == 3 })
+	waitFor(t, func() bool { return client.XLen(context.Background(), "otp:delivery").Val() == 0 })
+
+	for _, code := range sender.codes {
+		if code != "123456" {
+			t.Fatalf("expected decrypted code, got %q", code)
+		}
+	}
+	if n := client.XLen(context.Background(), "{otp:delivery}:dead").Val(); n != 0 {
+		t.Fatalf("expected no dead letters, got %d", n)
+	}
+}
+
+func TestWorker_DeadLettersAfterMaxAttempts(t *testing.T) {
+	sender := &recordingSender{failures: 100}
+	queue, client, stop := runWorker(t, sender, 3)
+	defer stop()
+
+	if err := queue.Send(context.Background(), "+989123456789", "123456"); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	waitFor(t, func() bool { return client.XLen(context.Background(), "{otp:delivery}:dead").Val() == 1 })
+
+	if sender.calls() != 3 {
+		t.Fatalf("expected 3 attempts, got %d", sender.calls())
+	}
+}
+
+func TestDeliveryQueue_DoesNotStorePlaintextCode(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	sealer, _ := sms.NewCodeSealer([]byte("0123456789abcdef"))
+	if err := sms.NewDeliveryQueue(client, sealer, nil, nil).Send(context.Background(), "+989123456789", "123456"); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	msgs, err := client.XRange(context.Background(), "otp:delivery", "-", "+").Result()
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("expected one queued job, got %v err=%v", msgs, err)
+	}
+	sealed, _ := msgs[0].Values["code"].(string)
+	if sealed == "123456" {
+		t.Fatalf("plaintext code stored in queue")
+	}
+	code, err := sealer.Open(sealed)
+	if err != nil || code != "123456" {
+		t.Fatalf("expected sealed code to open, got %q err=%v", code, err)
+	}
+	if _, err := sealer.Open("garbage"); !errors.Is(err, sms.ErrInvalidSealedCode) {
+		t.Fatalf("expected ErrInvalidSealedCode, got %v", err)
+	}
+}
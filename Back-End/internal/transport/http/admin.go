@@ -0,0 +1,221 @@
+package transport
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/panbeh/otp-backend/internal/domain/business"
+	"github.com/panbeh/otp-backend/internal/domain/usage"
+)
+
+const maxAdminListLimit = 200
+
+type BusinessAdmin interface {
+	Create(ctx context.Context, name string) (business.Business, error)
+	List(ctx context.Context, filter business.ListFilter) ([]business.Business, error)
+	Get(ctx context.Context, id string) (business.Business, error)
+	Rename(ctx context.Context, id, name string) (business.Business, error)
+	Suspend(ctx context.Context, id string) (business.Business, error)
+	Reactivate(ctx context.Context, id string) (business.Business, error)
+	Delete(ctx context.Context, id string) (business.Business, error)
+	SetPlan(ctx context.Context, id, planID string) (business.Business, error)
+}
+
+type createBusinessRequest struct {
+	Name string `json:"name"`
+}
+
+type renameBusinessRequest struct {
+	Name string `json:"name"`
+}
+
+type setPlanRequest struct {
+	PlanID string `json:"plan_id"`
+}
+
+type businessResponse struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Status      business.Status `json:"status"`
+	TokenPrefix string          `json:"token_prefix"`
+	PlanID      string          `json:"plan_id,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	DeletedAt   *time.Time      `json:"deleted_at,omitempty"`
+}
+
+// createdBusinessResponse carries the raw token, shown only when the business is created.
+type createdBusinessResponse struct {
+	businessResponse
+	Token string `json:"token"`
+}
+
+func newBusinessResponse(b business.Business) businessResponse {
+	return businessResponse{
+		ID:          b.ID,
+		Name:        b.Name,
+		Status:      b.Status,
+		TokenPrefix: b.TokenPrefix,
+		PlanID:      b.PlanID,
+		CreatedAt:   b.CreatedAt,
+		DeletedAt:   b.DeletedAt,
+	}
+}
+
+// AdminHandler serves the operator routes under /admin/businesses. They are guarded by
+// a single operator token (ADMIN_TOKEN), not by business API keys; with no token
+// configured every request is rejected.
+type AdminHandler struct {
+	businesses BusinessAdmin
+	token      string
+	logger     *slog.Logger
+}
+
+func NewAdminHandler(businesses BusinessAdmin, token string, logger *slog.Logger) *AdminHandler {
+	return &AdminHandler{businesses: businesses, token: token, logger: logger}
+}
+
+func (h *AdminHandler) Register(e *echo.Echo) {
+	g := e.Group("/admin/businesses", h.requireAdmin)
+	g.POST("", h.create)
+	g.GET("", h.list)
+	g.GET("/:id", h.get)
+	g.PATCH("/:id", h.rename)
+	g.POST("/:id/suspend", h.suspend)
+	g.POST("/:id/reactivate", h.reactivate)
+	g.DELETE("/:id", h.delete)
+	g.PUT("/:id/plan", h.setPlan)
+}
+
+func (h *AdminHandler) requireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token, ok := bearerToken(c.Request())
+		if !ok || h.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) != 1 {
+			return echo.NewHTTPError(http.StatusUnauthorized)
+		}
+		return next(c)
+	}
+}
+
+func (h *AdminHandler) create(c echo.Context) error {
+	var req createBusinessRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+	b, err := h.businesses.Create(c.Request().Context(), req.Name)
+	if err != nil {
+		return h.fail(c, "business_create_failed", err)
+	}
+	return c.JSON(http.StatusCreated, createdBusinessResponse{businessResponse: newBusinessResponse(b), Token: b.Token})
+}
+
+func (h *AdminHandler) list(c echo.Context) error {
+	var filter business.ListFilter
+	if v := c.QueryParam("status"); v != "" {
+		status, err := business.NewStatus(v)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid status")
+		}
+		filter.Status = status
+	}
+	var err error
+	if filter.Limit, err = queryInt(c, "limit"); err != nil || filter.Limit < 0 || filter.Limit > maxAdminListLimit {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid limit")
+	}
+	if filter.Offset, err = queryInt(c, "offset"); err != nil || filter.Offset < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid offset")
+	}
+
+	bs, err := h.businesses.List(c.Request().Context(), filter)
+	if err != nil {
+		return h.fail(c, "business_list_failed", err)
+	}
+	res := make([]businessResponse, 0, len(bs))
+	for _, b := range bs {
+		res = append(res, newBusinessResponse(b))
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+func (h *AdminHandler) get(c echo.Context) error {
+	b, err := h.businesses.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return h.fail(c, "business_get_failed", err)
+	}
+	return c.JSON(http.StatusOK, newBusinessResponse(b))
+}
+
+func (h *AdminHandler) rename(c echo.Context) error {
+	var req renameBusinessRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+	b, err := h.businesses.Rename(c.Request().Context(), c.Param("id"), req.Name)
+	if err != nil {
+		return h.fail(c, "business_rename_failed", err)
+	}
+	return c.JSON(http.StatusOK, newBusinessResponse(b))
+}
+
+func (h *AdminHandler) suspend(c echo.Context) error {
+	b, err := h.businesses.Suspend(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return h.fail(c, "business_suspend_failed", err)
+	}
+	return c.JSON(http.StatusOK, newBusinessResponse(b))
+}
+
+func (h *AdminHandler) reactivate(c echo.Context) error {
+	b, err := h.businesses.Reactivate(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return h.fail(c, "business_reactivate_failed", err)
+	}
+	return c.JSON(http.StatusOK, newBusinessResponse(b))
+}
+
+func (h *AdminHandler) delete(c echo.Context) error {
+	if _, err := h.businesses.Delete(c.Request().Context(), c.Param("id")); err != nil {
+		return h.fail(c, "business_delete_failed", err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (h *AdminHandler) setPlan(c echo.Context) error {
+	var req setPlanRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+	b, err := h.businesses.SetPlan(c.Request().Context(), c.Param("id"), req.PlanID)
+	if err != nil {
+		return h.fail(c, "business_set_plan_failed", err)
+	}
+	return c.JSON(http.StatusOK, newBusinessResponse(b))
+}
+
+// fail maps domain errors to HTTP errors and logs anything unexpected.
+func (h *AdminHandler) fail(c echo.Context, msg string, err error) error {
+	switch {
+	case errors.Is(err, business.ErrNotFound):
+		return echo.NewHTTPError(http.StatusNotFound)
+	case errors.Is(err, business.ErrInvalidName), errors.Is(err, usage.ErrPlanNotFound):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, business.ErrInvalidTransition):
+		return echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+	h.logger.ErrorContext(c.Request().Context(), msg, slog.Any("err", err))
+	return echo.NewHTTPError(http.StatusInternalServerError)
+}
+
+func queryInt(c echo.Context, name string) (int, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(v)
+}
@@ -0,0 +1,205 @@
+package transport_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/panbeh/otp-backend/internal/domain/business"
+	"github.com/panbeh/otp-backend/internal/domain/usage"
+	transport "github.com/panbeh/otp-backend/internal/transport/http"
+)
+
+// fakeAdmin keeps businesses in memory and applies the real domain transitions.
+type fakeAdmin struct {
+	svc        *business.Service
+	businesses map[string]business.Business
+	filter     business.ListFilter
+}
+
+func newFakeAdmin(bs ...business.Business) *fakeAdmin {
+	f := &fakeAdmin{svc: business.NewService(business.ServiceConfig{}), businesses: map[string]business.Business{}}
+	for _, b := range bs {
+		f.businesses[b.ID] = b
+	}
+	return f
+}
+
+func (f *fakeAdmin) Create(ctx context.Context, name string) (business.Business, error) {
+	b, err := f.svc.NewBusiness(name)
+	if err != nil {
+		return business.Business{}, err
+	}
+	f.businesses[b.ID] = b
+	return b, nil
+}
+
+func (f *fakeAdmin) List(ctx context.Context, filter business.ListFilter) ([]business.Business, error) {
+	f.filter = filter
+	var out []business.Business
+	for _, b := range f.businesses {
+		out = append(out, b)
+	}
+	return out, nil
+}
+
+func (f *fakeAdmin) Get(ctx context.Context, id string) (business.Business, error) {
+	b, ok := f.businesses[id]
+	if !ok {
+		return business.Business{}, business.ErrNotFound
+	}
+	return b, nil
+}
+
+func (f *fakeAdmin) apply(id string, op func(business.Business) (business.Business, error)) (business.Business, error) {
+	b, err := f.Get(context.Background(), id)
+	if err != nil {
+		return business.Business{}, err
+	}
+	if b, err = op(b); err != nil {
+		return business.Business{}, err
+	}
+	f.businesses[id] = b
+	return b, nil
+}
+
+func (f *fakeAdmin) Rename(ctx context.Context, id, name string) (business.Business, error) {
+	return f.apply(id, func(b business.Business) (business.Business, error) { return f.svc.Rename(b, name) })
+}
+
+func (f *fakeAdmin) Suspend(ctx context.Context, id string) (business.Business, error) {
+	return f.apply(id, f.svc.Suspend)
+}
+
+func (f *fakeAdmin) Reactivate(ctx context.Context, id string) (business.Business, error) {
+	return f.apply(id, f.svc.Reactivate)
+}
+
+func (f *fakeAdmin) Delete(ctx context.Context, id string) (business.Business, error) {
+	return f.apply(id, f.svc.Delete)
+}
+
+func (f *fakeAdmin) SetPlan(ctx context.Context, id, planID string) (business.Business, error) {
+	if planID != "" && planID != "starter" {
+		return business.Business{}, usage.ErrPlanNotFound
+	}
+	return f.apply(id, func(b business.Business) (business.Business, error) {
+		b.PlanID = planID
+		return b, nil
+	})
+}
+
+func newAdminServer(admin transport.BusinessAdmin, token string) *echo.Echo {
+	e := echo.New()
+	transport.NewAdminHandler(admin, token, slog.New(slog.NewTextHandler(io.Discard, nil))).Register(e)
+	return e
+}
+
+func TestAdminHandler_RequiresAdminToken(t *testing.T) {
+	admin := newFakeAdmin(business.Business{ID: "b1", Status: business.StatusActive})
+
+	e := newAdminServer(admin, "ops-secret")
+	for _, key := range []string{"", "wrong"} {
+		if rec := serve(e, http.MethodGet, "/admin/businesses/b1", key, ""); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401 for key %q, got %d", key, rec.Code)
+		}
+	}
+
+	// Without a configured token the routes stay closed.
+	e = newAdminServer(admin, "")
+	if rec := serve(e, http.MethodGet, "/admin/businesses/b1", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with no admin token configured, got %d", rec.Code)
+	}
+}
+
+func TestAdminHandler_Lifecycle(t *testing.T) {
+	admin := newFakeAdmin(business.Business{ID: "b1", Name: "Acme", Status: business.StatusActive})
+	e := newAdminServer(admin, "ops-secret")
+
+	rec := serve(e, http.MethodPatch, "/admin/businesses/b1", "ops-secret", `{"name":"Acme Pay"}`)
+	if rec.Code != http.StatusOK || admin.businesses["b1"].Name != "Acme Pay" {
+		t.Fatalf("expected rename, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = serve(e, http.MethodPost, "/admin/businesses/b1/suspend", "ops-secret", "")
+	var body struct {
+		Status string `json:"status"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusOK || body.Status != "suspended" {
+		t.Fatalf("expected suspended, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(e, http.MethodPost, "/admin/businesses/b1/suspend", "ops-secret", ""); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 suspending twice, got %d", rec.Code)
+	}
+	if rec := serve(e, http.MethodPost, "/admin/businesses/b1/reactivate", "ops-secret", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected reactivate, got %d", rec.Code)
+	}
+
+	if rec := serve(e, http.MethodDelete, "/admin/businesses/b1", "ops-secret", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 on delete, got %d", rec.Code)
+	}
+	if b := admin.businesses["b1"]; b.Status != business.StatusDeleted || b.DeletedAt == nil {
+		t.Fatalf("expected soft delete, got %#v", b)
+	}
+	if rec := serve(e, http.MethodGet, "/admin/businesses/missing", "ops-secret", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown business, got %d", rec.Code)
+	}
+}
+
+func TestAdminHandler_Create(t *testing.T) {
+	admin := newFakeAdmin()
+	e := newAdminServer(admin, "ops-secret")
+
+	rec := serve(e, http.MethodPost, "/admin/businesses", "ops-secret", `{"name":"Acme"}`)
+	var body struct {
+		ID          string `json:"id"`
+		Token       string `json:"token"`
+		TokenPrefix string `json:"token_prefix"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusCreated || body.Token == "" || body.TokenPrefix == "" || admin.businesses[body.ID].Name != "Acme" {
+		t.Fatalf("expected the business and its token, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(e, http.MethodPost, "/admin/businesses", "ops-secret", `{"name":" "}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an empty name, got %d", rec.Code)
+	}
+}
+
+func TestAdminHandler_ListFilter(t *testing.T) {
+	admin := newFakeAdmin()
+	e := newAdminServer(admin, "ops-secret")
+
+	rec := serve(e, http.MethodGet, "/admin/businesses?status=suspended&limit=10&offset=20", "ops-secret", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if admin.filter != (business.ListFilter{Status: business.StatusSuspended, Limit: 10, Offset: 20}) {
+		t.Fatalf("unexpected filter: %+v", admin.filter)
+	}
+	if rec := serve(e, http.MethodGet, "/admin/businesses?status=paused", "ops-secret", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown status, got %d", rec.Code)
+	}
+}
+
+func TestAdminHandler_SetPlan(t *testing.T) {
+	admin := newFakeAdmin(business.Business{ID: "b1", Status: business.StatusActive})
+	e := newAdminServer(admin, "ops-secret")
+
+	rec := serve(e, http.MethodPut, "/admin/businesses/b1/plan", "ops-secret", `{"plan_id":"starter"}`)
+	if rec.Code != http.StatusOK || admin.businesses["b1"].PlanID != "starter" {
+		t.Fatalf("expected plan to be attached, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(e, http.MethodPut, "/admin/businesses/b1/plan", "ops-secret", `{"plan_id":"gold"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown plan, got %d", rec.Code)
+	}
+	rec = serve(e, http.MethodPut, "/admin/businesses/b1/plan", "ops-secret", `{"plan_id":""}`)
+	if rec.Code != http.StatusOK || admin.businesses["b1"].PlanID != "" {
+		t.Fatalf("expected plan to be detached, got %d: %s", rec.Code, rec.Body)
+	}
+}
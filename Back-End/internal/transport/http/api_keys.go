@@ -0,0 +1,117 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/panbeh/otp-backend/internal/domain/apikey"
+)
+
+type APIKeyManager interface {
+	Create(ctx context.Context, issuer apikey.APIKey, name string, scopes []string) (apikey.APIKey, error)
+	List(ctx context.Context, businessID string) ([]apikey.APIKey, error)
+	Revoke(ctx context.Context, businessID, id string) error
+}
+
+type createAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type apiKeyResponse struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	Key        string         `json:"key,omitempty"`
+	Prefix     string         `json:"prefix"`
+	Scopes     []apikey.Scope `json:"scopes"`
+	CreatedAt  time.Time      `json:"created_at"`
+	LastUsedAt *time.Time     `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time     `json:"revoked_at,omitempty"`
+}
+
+func newAPIKeyResponse(k apikey.APIKey) apiKeyResponse {
+	return apiKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Key:        k.Key,
+		Prefix:     k.KeyPrefix,
+		Scopes:     k.Scopes,
+		CreatedAt:  k.CreatedAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+	}
+}
+
+// APIKeyHandler lets a business manage its own API keys under /business/api-keys.
+// Listing needs admin:read; creating and revoking need admin:write. A new key can only
+// hold scopes the key creating it has.
+type APIKeyHandler struct {
+	keys   APIKeyManager
+	auth   Authenticator
+	logger *slog.Logger
+}
+
+func NewAPIKeyHandler(keys APIKeyManager, auth Authenticator, logger *slog.Logger) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys, auth: auth, logger: logger}
+}
+
+func (h *APIKeyHandler) Register(e *echo.Echo) {
+	g := e.Group("/business/api-keys")
+	g.GET("", h.list, h.auth.RequireScope(apikey.ScopeAdminRead))
+	g.POST("", h.create, h.auth.RequireScope(apikey.ScopeAdminWrite))
+	g.DELETE("/:id", h.revoke, h.auth.RequireScope(apikey.ScopeAdminWrite))
+}
+
+func (h *APIKeyHandler) create(c echo.Context) error {
+	caller, _ := APIKeyFromContext(c)
+	var req createAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	k, err := h.keys.Create(c.Request().Context(), caller, req.Name, req.Scopes)
+	switch {
+	case err == nil:
+		return c.JSON(http.StatusCreated, newAPIKeyResponse(k))
+	case errors.Is(err, apikey.ErrInvalidName), errors.Is(err, apikey.ErrInvalidScope):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, apikey.ErrScopeDenied):
+		return echo.NewHTTPError(http.StatusForbidden, "cannot grant scopes the calling key lacks")
+	}
+	return h.internalError(c, "api_key_create_failed", err)
+}
+
+func (h *APIKeyHandler) list(c echo.Context) error {
+	caller, _ := APIKeyFromContext(c)
+	keys, err := h.keys.List(c.Request().Context(), caller.BusinessID)
+	if err != nil {
+		return h.internalError(c, "api_key_list_failed", err)
+	}
+	res := make([]apiKeyResponse, 0, len(keys))
+	for _, k := range keys {
+		res = append(res, newAPIKeyResponse(k))
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+func (h *APIKeyHandler) revoke(c echo.Context) error {
+	caller, _ := APIKeyFromContext(c)
+	err := h.keys.Revoke(c.Request().Context(), caller.BusinessID, c.Param("id"))
+	switch {
+	case err == nil:
+		return c.NoContent(http.StatusNoContent)
+	case errors.Is(err, apikey.ErrNotFound):
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	return h.internalError(c, "api_key_revoke_failed", err)
+}
+
+func (h *APIKeyHandler) internalError(c echo.Context, msg string, err error) error {
+	h.logger.ErrorContext(c.Request().Context(), msg, slog.Any("err", err))
+	return echo.NewHTTPError(http.StatusInternalServerError)
+}
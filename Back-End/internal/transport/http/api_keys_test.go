@@ -0,0 +1,146 @@
+package transport_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/panbeh/otp-backend/internal/domain/apikey"
+	transport "github.com/panbeh/otp-backend/internal/transport/http"
+)
+
+// fakeKeys knows one key per raw secret and applies the real scope rules.
+type fakeKeys struct {
+	keys    map[string]apikey.APIKey
+	created []string
+	revoked []string
+}
+
+func (f *fakeKeys) Resolve(ctx context.Context, key string, scope apikey.Scope) (apikey.APIKey, error) {
+	k, ok := f.keys[key]
+	if !ok {
+		return apikey.APIKey{}, apikey.ErrNotFound
+	}
+	return k, apikey.NewService(apikey.ServiceConfig{}).Authorize(k, scope)
+}
+
+func (f *fakeKeys) Create(ctx context.Context, issuer apikey.APIKey, name string, scopes []string) (apikey.APIKey, error) {
+	k, err := apikey.NewService(apikey.ServiceConfig{}).NewKeyFor(issuer, name, scopes)
+	if err == nil {
+		f.created = append(f.created, issuer.BusinessID+"/"+name)
+	}
+	return k, err
+}
+
+func (f *fakeKeys) List(ctx context.Context, businessID string) ([]apikey.APIKey, error) {
+	var out []apikey.APIKey
+	for _, k := range f.keys {
+		if k.BusinessID == businessID {
+			out = append(out, k)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeKeys) Revoke(ctx context.Context, businessID, id string) error {
+	f.revoked = append(f.revoked, businessID+"/"+id)
+	return nil
+}
+
+func newAPIKeyServer(keys *fakeKeys) *echo.Echo {
+	e := echo.New()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	transport.NewAPIKeyHandler(keys, transport.Authenticator{Keys: keys, Logger: logger}, logger).Register(e)
+	return e
+}
+
+func serve(e *echo.Echo, method, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if key != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+key)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPIKeyHandler_EnforcesScopes(t *testing.T) {
+	keys := &fakeKeys{keys: map[string]apikey.APIKey{
+		"reader": {ID: "k1", BusinessID: "b1", Scopes: []apikey.Scope{apikey.ScopeAdminRead}},
+		"sender": {ID: "k2", BusinessID: "b1", Scopes: []apikey.Scope{apikey.ScopeOTPSend}},
+	}}
+	e := newAPIKeyServer(keys)
+
+	if rec := serve(e, http.MethodGet, "/business/api-keys", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without key, got %d", rec.Code)
+	}
+	if rec := serve(e, http.MethodGet, "/business/api-keys", "unknown", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for unknown key, got %d", rec.Code)
+	}
+	if rec := serve(e, http.MethodGet, "/business/api-keys", "sender", ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without admin:read, got %d", rec.Code)
+	}
+	if rec := serve(e, http.MethodGet, "/business/api-keys", "reader", ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 with admin:read, got %d", rec.Code)
+	}
+	if rec := serve(e, http.MethodDelete, "/business/api-keys/k2", "reader", ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 revoking without admin:write, got %d", rec.Code)
+	}
+	if len(keys.revoked) != 0 {
+		t.Fatalf("expected nothing revoked, got %v", keys.revoked)
+	}
+}
+
+func TestAPIKeyHandler_CreateReturnsKeyOnce(t *testing.T) {
+	keys := &fakeKeys{keys: map[string]apikey.APIKey{
+		"admin": {ID: "k1", BusinessID: "b1", Scopes: []apikey.Scope{apikey.ScopeAdminWrite, apikey.ScopeOTPSend}},
+	}}
+	e := newAPIKeyServer(keys)
+
+	rec := serve(e, http.MethodPost, "/business/api-keys", "admin", `{"name":"staging","scopes":["otp:send"]}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Key    string   `json:"key"`
+		Prefix string   `json:"prefix"`
+		Scopes []string `json:"scopes"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Key == "" || !strings.HasPrefix(body.Key, body.Prefix) || len(body.Scopes) != 1 {
+		t.Fatalf("unexpected response: %+v", body)
+	}
+	if len(keys.created) != 1 || keys.created[0] != "b1/staging" {
+		t.Fatalf("expected key created for the caller's business, got %v", keys.created)
+	}
+
+	rec = serve(e, http.MethodPost, "/business/api-keys", "admin", `{"name":"staging","scopes":["root"]}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown scope, got %d", rec.Code)
+	}
+}
+
+func TestAPIKeyHandler_CreateRefusesScopesTheCallerLacks(t *testing.T) {
+	keys := &fakeKeys{keys: map[string]apikey.APIKey{
+		"admin": {ID: "k1", BusinessID: "b1", Scopes: []apikey.Scope{apikey.ScopeAdminWrite}},
+	}}
+	e := newAPIKeyServer(keys)
+
+	rec := serve(e, http.MethodPost, "/business/api-keys", "admin", `{"name":"escalate","scopes":["admin:write","admin:read"]}`)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a scope the caller lacks, got %d: %s", rec.Code, rec.Body)
+	}
+	if len(keys.created) != 0 {
+		t.Fatalf("expected no key created, got %v", keys.created)
+	}
+}
@@ -0,0 +1,138 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/panbeh/otp-backend/internal/domain/apikey"
+	"github.com/panbeh/otp-backend/internal/domain/business"
+	"github.com/panbeh/otp-backend/internal/service"
+)
+
+const apiKeyContextKey = "api_key"
+
+// Signed-request headers. The signature is hex HMAC-SHA256 over service.SigningPayload.
+const (
+	HeaderBusinessID         = "X-Business-ID"
+	HeaderSignature          = "X-Signature"
+	HeaderSignatureTimestamp = "X-Signature-Timestamp"
+	HeaderSignatureNonce     = "X-Signature-Nonce"
+)
+
+// maxSignedBodySize caps how much of a signed request is buffered to check its signature.
+const maxSignedBodySize = 1 << 20
+
+// KeyResolver authenticates a presented API key and checks it grants scope.
+type KeyResolver interface {
+	Resolve(ctx context.Context, key string, scope apikey.Scope) (apikey.APIKey, error)
+}
+
+// SignatureVerifier authenticates an HMAC-signed request.
+type SignatureVerifier interface {
+	Verify(ctx context.Context, r service.SignedRequest) (apikey.APIKey, error)
+}
+
+// Authenticator resolves the caller of business-scoped routes from a bearer API key
+// or, when Signatures is set, from an HMAC-signed request.
+type Authenticator struct {
+	Keys       KeyResolver
+	Signatures SignatureVerifier
+	Logger     *slog.Logger
+}
+
+// RequireScope authenticates the caller and rejects it unless it grants scope.
+// Handlers read the resolved key with APIKeyFromContext.
+func (a Authenticator) RequireScope(scope apikey.Scope) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			var (
+				k   apikey.APIKey
+				err error
+			)
+			if key, ok := bearerToken(c.Request()); ok {
+				k, err = a.Keys.Resolve(c.Request().Context(), key, scope)
+			} else if a.Signatures != nil && c.Request().Header.Get(HeaderSignature) != "" {
+				k, err = a.verifySignature(c, scope)
+			} else {
+				return echo.NewHTTPError(http.StatusUnauthorized)
+			}
+
+			switch {
+			case err == nil:
+			case errors.Is(err, apikey.ErrNotFound), errors.Is(err, apikey.ErrInvalidKey), errors.Is(err, apikey.ErrRevoked),
+				errors.Is(err, apikey.ErrInvalidSignature), errors.Is(err, apikey.ErrStaleSignature), errors.Is(err, apikey.ErrReplayedNonce):
+				return echo.NewHTTPError(http.StatusUnauthorized)
+			case errors.Is(err, apikey.ErrScopeDenied):
+				return echo.NewHTTPError(http.StatusForbidden, "api key lacks scope "+string(scope))
+			case errors.Is(err, business.ErrSuspended):
+				return errBusinessSuspended()
+			default:
+				a.Logger.ErrorContext(c.Request().Context(), "auth_resolve_failed", slog.Any("err", err))
+				return echo.NewHTTPError(http.StatusInternalServerError)
+			}
+
+			c.Set(apiKeyContextKey, k)
+			return next(c)
+		}
+	}
+}
+
+func (a Authenticator) verifySignature(c echo.Context, scope apikey.Scope) (apikey.APIKey, error) {
+	req := c.Request()
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxSignedBodySize+1))
+	if err != nil {
+		return apikey.APIKey{}, err
+	}
+	if len(body) > maxSignedBodySize {
+		return apikey.APIKey{}, apikey.ErrInvalidSignature
+	}
+	// Handlers still need to bind the body after it has been hashed.
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	k, err := a.Signatures.Verify(req.Context(), service.SignedRequest{
+		BusinessID: req.Header.Get(HeaderBusinessID),
+		Method:     req.Method,
+		Path:       req.URL.RequestURI(),
+		Timestamp:  req.Header.Get(HeaderSignatureTimestamp),
+		Nonce:      req.Header.Get(HeaderSignatureNonce),
+		Body:       body,
+		Signature:  req.Header.Get(HeaderSignature),
+	})
+	if err != nil {
+		return apikey.APIKey{}, err
+	}
+	if !k.HasScope(scope) {
+		return apikey.APIKey{}, apikey.ErrScopeDenied
+	}
+	return k, nil
+}
+
+// ReasonBusinessSuspended is the machine-readable "error" of the 403 returned to
+// suspended businesses, so clients can tell it apart from a missing scope.
+const ReasonBusinessSuspended = "business_suspended"
+
+func errBusinessSuspended() error {
+	return echo.NewHTTPError(http.StatusForbidden, map[string]string{
+		"error":   ReasonBusinessSuspended,
+		"message": "business is suspended",
+	})
+}
+func APIKeyFromContext(c echo.Context) (apikey.APIKey, bool) {
+	k, ok := c.Get(apiKeyContextKey).(apikey.APIKey)
+	return k, ok
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get(echo.HeaderAuthorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
@@ -0,0 +1,98 @@
+package transport_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/panbeh/otp-backend/internal/domain/apikey"
+	"github.com/panbeh/otp-backend/internal/domain/business"
+	"github.com/panbeh/otp-backend/internal/service"
+	transport "github.com/panbeh/otp-backend/internal/transport/http"
+)
+
+type fakeVerifier struct {
+	got service.SignedRequest
+	err error
+}
+
+func (f *fakeVerifier) Verify(ctx context.Context, r service.SignedRequest) (apikey.APIKey, error) {
+	f.got = r
+	if f.err != nil {
+		return apikey.APIKey{}, f.err
+	}
+	return apikey.APIKey{BusinessID: r.BusinessID, Scopes: apikey.AllScopes}, nil
+}
+
+func newSignedServer(verifier transport.SignatureVerifier) *echo.Echo {
+	e := echo.New()
+	auth := transport.Authenticator{
+		Keys:       &fakeKeys{},
+		Signatures: verifier,
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	e.POST("/echo", func(c echo.Context) error {
+		k, _ := transport.APIKeyFromContext(c)
+		body, _ := io.ReadAll(c.Request().Body)
+		return c.String(http.StatusOK, k.BusinessID+":"+string(body))
+	}, auth.RequireScope(apikey.ScopeOTPSend))
+	return e
+}
+
+func TestAuthenticator_SignedRequest(t *testing.T) {
+	verifier := &fakeVerifier{}
+	e := newSignedServer(verifier)
+
+	req := httptest.NewRequest(http.MethodPost, "/echo?x=1", strings.NewReader(`{"a":1}`))
+	req.Header.Set(transport.HeaderBusinessID, "b1")
+	req.Header.Set(transport.HeaderSignatureTimestamp, "1700000000")
+	req.Header.Set(transport.HeaderSignatureNonce, "nonce-0000000001")
+	req.Header.Set(transport.HeaderSignature, "abc")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || rec.Body.String() != `b1:{"a":1}` {
+		t.Fatalf("expected handler to see business and body, got %d %q", rec.Code, rec.Body)
+	}
+	got := verifier.got
+	if got.Method != http.MethodPost || got.Path != "/echo?x=1" || got.Timestamp != "1700000000" ||
+		got.Nonce != "nonce-0000000001" || got.Signature != "abc" || string(got.Body) != `{"a":1}` {
+		t.Fatalf("unexpected signed request: %+v", got)
+	}
+}
+
+func TestAuthenticator_SignedRequest_Rejected(t *testing.T) {
+	for _, err := range []error{apikey.ErrInvalidSignature, apikey.ErrStaleSignature, apikey.ErrReplayedNonce} {
+		e := newSignedServer(&fakeVerifier{err: err})
+		req := httptest.NewRequest(http.MethodPost, "/echo", nil)
+		req.Header.Set(transport.HeaderSignature, "abc")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401 for %v, got %d", err, rec.Code)
+		}
+	}
+}
+
+func TestAuthenticator_SuspendedBusiness(t *testing.T) {
+	e := newSignedServer(&fakeVerifier{err: business.ErrSuspended})
+	req := httptest.NewRequest(http.MethodPost, "/echo", nil)
+	req.Header.Set(transport.HeaderSignature, "abc")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var body struct {
+		Error string `json:"error"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusForbidden || body.Error != transport.ReasonBusinessSuspended {
+		t.Fatalf("expected 403 business_suspended, got %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,64 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/panbeh/otp-backend/internal/domain/business"
+)
+
+type TokenRotator interface {
+	Rotate(ctx context.Context, token string) (business.Business, error)
+}
+
+type rotateTokenResponse struct {
+	Token                  string     `json:"token"`
+	PreviousTokenExpiresAt *time.Time `json:"previous_token_expires_at,omitempty"`
+}
+
+// TokenHandler serves POST /business/token/rotate. The caller authenticates with its
+// current token as a bearer token and receives the replacement in the response.
+type TokenHandler struct {
+	rotator TokenRotator
+	logger  *slog.Logger
+}
+
+func NewTokenHandler(rotator TokenRotator, logger *slog.Logger) *TokenHandler {
+	return &TokenHandler{rotator: rotator, logger: logger}
+}
+
+func (h *TokenHandler) Register(e *echo.Echo) {
+	e.POST("/business/token/rotate", h.rotate)
+}
+
+func (h *TokenHandler) rotate(c echo.Context) error {
+	token, ok := bearerToken(c.Request())
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized)
+	}
+
+	b, err := h.rotator.Rotate(c.Request().Context(), token)
+	switch {
+	case err == nil:
+	case errors.Is(err, business.ErrInvalidToken), errors.Is(err, business.ErrNotFound):
+		return echo.NewHTTPError(http.StatusUnauthorized)
+	case errors.Is(err, business.ErrSuspended):
+		return errBusinessSuspended()
+	case errors.Is(err, business.ErrTokenConflict):
+		return echo.NewHTTPError(http.StatusConflict, "token was rotated concurrently")
+	default:
+		h.logger.ErrorContext(c.Request().Context(), "token_rotation_failed", slog.Any("err", err))
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+
+	res := rotateTokenResponse{Token: b.Token}
+	if !b.PreviousTokenExpiresAt.IsZero() {
+		res.PreviousTokenExpiresAt = &b.PreviousTokenExpiresAt
+	}
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,78 @@
+package transport_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/panbeh/otp-backend/internal/domain/business"
+	transport "github.com/panbeh/otp-backend/internal/transport/http"
+)
+
+type fakeRotator struct {
+	token string
+	err   error
+}
+
+func (f *fakeRotator) Rotate(ctx context.Context, token string) (business.Business, error) {
+	f.token = token
+	if f.err != nil {
+		return business.Business{}, f.err
+	}
+	return business.Business{Token: "new", PreviousTokenHash: business.HashToken(token), PreviousTokenExpiresAt: time.Unix(100, 0).UTC()}, nil
+}
+
+func rotate(t *testing.T, rotator transport.TokenRotator, auth string) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	transport.NewTokenHandler(rotator, slog.New(slog.NewTextHandler(io.Discard, nil))).Register(e)
+
+	req := httptest.NewRequest(http.MethodPost, "/business/token/rotate", nil)
+	if auth != "" {
+		req.Header.Set(echo.HeaderAuthorization, auth)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestTokenHandler_Rotate(t *testing.T) {
+	rotator := &fakeRotator{}
+	rec := rotate(t, rotator, "Bearer old")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if rotator.token != "old" {
+		t.Fatalf("expected presented token to be passed through, got %q", rotator.token)
+	}
+	var body struct {
+		Token                  string    `json:"token"`
+		PreviousTokenExpiresAt time.Time `json:"previous_token_expires_at"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Token != "new" || !body.PreviousTokenExpiresAt.Equal(time.Unix(100, 0)) {
+		t.Fatalf("unexpected response: %+v", body)
+	}
+}
+
+func TestTokenHandler_Rotate_RejectsUnauthenticated(t *testing.T) {
+	if rec := rotate(t, &fakeRotator{}, ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", rec.Code)
+	}
+	if rec := rotate(t, &fakeRotator{err: business.ErrInvalidToken}, "Bearer grace"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for grace token, got %d", rec.Code)
+	}
+	if rec := rotate(t, &fakeRotator{err: business.ErrTokenConflict}, "Bearer old"); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 on concurrent rotation, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,65 @@
+package transport
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sort"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HealthChecker probes one dependency. Details (pool stats, say) are reported as-is,
+// also when the check fails.
+type HealthChecker interface {
+	Check(ctx context.Context) (details any, err error)
+}
+
+type healthCheckResponse struct {
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+	Details any    `json:"details,omitempty"`
+}
+
+type healthResponse struct {
+	Status string                         `json:"status"`
+	Checks map[string]healthCheckResponse `json:"checks"`
+}
+
+// HealthHandler serves GET /healthz: 200 when every dependency answers, 503 otherwise.
+// It is unauthenticated, so failures are reported without internals and logged instead.
+type HealthHandler struct {
+	checks map[string]HealthChecker
+	logger *slog.Logger
+}
+
+func NewHealthHandler(checks map[string]HealthChecker, logger *slog.Logger) *HealthHandler {
+	return &HealthHandler{checks: checks, logger: logger}
+}
+
+func (h *HealthHandler) Register(e *echo.Echo) {
+	e.GET("/healthz", h.health)
+}
+
+func (h *HealthHandler) health(c echo.Context) error {
+	ctx := c.Request().Context()
+	res := healthResponse{Status: "ok", Checks: make(map[string]healthCheckResponse, len(h.checks))}
+	code := http.StatusOK
+
+	names := make([]string, 0, len(h.checks))
+	for name := range h.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		details, err := h.checks[name].Check(ctx)
+		check := healthCheckResponse{Status: "ok", Details: details}
+		if err != nil {
+			h.logger.WarnContext(ctx, "health_check_failed", slog.String("check", name), slog.Any("err", err))
+			check.Status, check.Error = "down", "unreachable"
+			res.Status, code = "down", http.StatusServiceUnavailable
+		}
+		res.Checks[name] = check
+	}
+	return c.JSON(code, res)
+}
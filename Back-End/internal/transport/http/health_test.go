@@ -0,0 +1,53 @@
+package transport_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	transport "github.com/panbeh/otp-backend/internal/transport/http"
+)
+
+type fakeCheck struct {
+	err error
+}
+
+func (f fakeCheck) Check(ctx context.Context) (any, error) {
+	return map[string]int{"open": 3}, f.err
+}
+
+func TestHealthHandler(t *testing.T) {
+	checks := map[string]transport.HealthChecker{"postgres": fakeCheck{}}
+	e := echo.New()
+	transport.NewHealthHandler(checks, slog.New(slog.NewTextHandler(io.Discard, nil))).Register(e)
+
+	rec := serve(e, http.MethodGet, "/healthz", "", "")
+	var body struct {
+		Status string `json:"status"`
+		Checks map[string]struct {
+			Status  string         `json:"status"`
+			Error   string         `json:"error"`
+			Details map[string]int `json:"details"`
+		} `json:"checks"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusOK || body.Status != "ok" || body.Checks["postgres"].Details["open"] != 3 {
+		t.Fatalf("expected healthy response with stats, got %d: %s", rec.Code, rec.Body)
+	}
+
+	checks["postgres"] = fakeCheck{err: errors.New("dial tcp 10.0.0.5:5432: connection refused")}
+	rec = serve(e, http.MethodGet, "/healthz", "", "")
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusServiceUnavailable || body.Checks["postgres"].Status != "down" {
+		t.Fatalf("expected 503, got %d: %s", rec.Code, rec.Body)
+	}
+	if body.Checks["postgres"].Error != "unreachable" {
+		t.Fatalf("expected the raw error to stay out of the response, got %s", rec.Body)
+	}
+}
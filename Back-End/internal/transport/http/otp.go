@@ -0,0 +1,180 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/panbeh/otp-backend/internal/domain/apikey"
+	"github.com/panbeh/otp-backend/internal/domain/business"
+	"github.com/panbeh/otp-backend/internal/domain/delivery"
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+	"github.com/panbeh/otp-backend/internal/service"
+	"github.com/panbeh/otp-backend/internal/sms"
+)
+
+type OTPSender interface {
+	Send(ctx context.Context, businessID, phone string) (service.SentOTP, error)
+	Verify(ctx context.Context, businessID, phone, code string) (bool, error)
+	Request(ctx context.Context, businessID, id string) (delivery.Delivery, error)
+}
+
+type sendOTPRequest struct {
+	Phone string `json:"phone"`
+}
+
+type sendOTPResponse struct {
+	RequestID string    `json:"request_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type verifyOTPRequest struct {
+	Phone string `json:"phone"`
+	Code  string `json:"code"`
+}
+
+type verifyOTPResponse struct {
+	Verified bool `json:"verified"`
+}
+
+type otpRequestResponse struct {
+	ID            string          `json:"id"`
+	Phone         string          `json:"phone"`
+	Status        delivery.Status `json:"status"`
+	Provider      string          `json:"provider,omitempty"`
+	FailureReason string          `json:"failure_reason,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	SentAt        *time.Time      `json:"sent_at,omitempty"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+}
+
+func newOTPRequestResponse(d delivery.Delivery) otpRequestResponse {
+	return otpRequestResponse{
+		ID:            d.ID,
+		Phone:         d.MaskedPhone,
+		Status:        d.Status,
+		Provider:      d.Provider,
+		FailureReason: d.FailureReason,
+		CreatedAt:     d.CreatedAt,
+		UpdatedAt:     d.UpdatedAt,
+		SentAt:        d.SentAt,
+		DeliveredAt:   d.DeliveredAt,
+	}
+}
+
+// OTPHandler serves POST /otp/send and POST /otp/verify for the business of the caller's
+// API key or signed request, which needs otp:send and otp:verify respectively. GET /otp/requests/:id
+// reports the delivery status of a send by the request_id it returned and needs otp:send.
+// Sending and verifying answer 403 business_suspended while the business is suspended.
+type OTPHandler struct {
+	otps   OTPSender
+	auth   Authenticator
+	logger *slog.Logger
+}
+
+func NewOTPHandler(otps OTPSender, auth Authenticator, logger *slog.Logger) *OTPHandler {
+	return &OTPHandler{otps: otps, auth: auth, logger: logger}
+}
+
+func (h *OTPHandler) Register(e *echo.Echo) {
+	g := e.Group("/otp")
+	g.POST("/send", h.send, h.auth.RequireScope(apikey.ScopeOTPSend))
+	g.POST("/verify", h.verify, h.auth.RequireScope(apikey.ScopeOTPVerify))
+	g.GET("/requests/:id", h.request, h.auth.RequireScope(apikey.ScopeOTPSend))
+}
+
+func (h *OTPHandler) send(c echo.Context) error {
+	caller, _ := APIKeyFromContext(c)
+	var req sendOTPRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	sent, err := h.otps.Send(c.Request().Context(), caller.BusinessID, req.Phone)
+	if err != nil {
+		return h.fail(c, "otp_send_failed", err)
+	}
+	return c.JSON(http.StatusAccepted, sendOTPResponse{RequestID: sent.RequestID, ExpiresAt: sent.ExpiresAt})
+}
+
+func (h *OTPHandler) verify(c echo.Context) error {
+	caller, _ := APIKeyFromContext(c)
+	var req verifyOTPRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	ok, err := h.otps.Verify(c.Request().Context(), caller.BusinessID, req.Phone, req.Code)
+	if err != nil {
+		return h.fail(c, "otp_verify_failed", err)
+	}
+	return c.JSON(http.StatusOK, verifyOTPResponse{Verified: ok})
+}
+
+func (h *OTPHandler) request(c echo.Context) error {
+	caller, _ := APIKeyFromContext(c)
+	d, err := h.otps.Request(c.Request().Context(), caller.BusinessID, c.Param("id"))
+	if err != nil {
+		return h.fail(c, "otp_request_get_failed", err)
+	}
+	return c.JSON(http.StatusOK, newOTPRequestResponse(d))
+}
+
+// Machine-readable "error" values of the 429s returned by /otp/send. ReasonRateLimited
+// means sends to the phone number are throttled and "reason" names the limit that was
+// hit; ReasonQuotaExceeded means the business used up its plan's monthly quota.
+const (
+	ReasonRateLimited   = "rate_limited"
+	ReasonQuotaExceeded = "quota_exceeded"
+)
+
+// fail maps domain and delivery errors to HTTP errors and logs anything unexpected.
+func (h *OTPHandler) fail(c echo.Context, msg string, err error) error {
+	var rateLimit *otp.RateLimitError
+	switch {
+	case errors.As(err, &rateLimit):
+		return errRateLimited(c, rateLimit)
+	case errors.Is(err, business.ErrSuspended), errors.Is(err, otp.ErrBusinessSuspended):
+		return errBusinessSuspended()
+	case errors.Is(err, business.ErrNotFound):
+		return echo.NewHTTPError(http.StatusUnauthorized)
+	case errors.Is(err, otp.ErrInvalidPhone), errors.Is(err, otp.ErrInvalidCode), errors.Is(err, otp.ErrCountryNotAllowed):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, otp.ErrTooManyAttempts):
+		return echo.NewHTTPError(http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, delivery.ErrNotFound):
+		return echo.NewHTTPError(http.StatusNotFound)
+	case errors.Is(err, sms.ErrDeliveryFailed), errors.Is(err, sms.ErrNoProviderAvailable):
+		h.logger.WarnContext(c.Request().Context(), msg, slog.Any("err", err))
+		return echo.NewHTTPError(http.StatusBadGateway, "sms delivery failed")
+	}
+	h.logger.ErrorContext(c.Request().Context(), msg, slog.Any("err", err))
+	return echo.NewHTTPError(http.StatusInternalServerError)
+}
+
+// errRateLimited answers 429 with Retry-After in whole seconds, rounded up so a client
+// that waits exactly that long isn't throttled again.
+func errRateLimited(c echo.Context, err *otp.RateLimitError) error {
+	seconds := int64((err.RetryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Response().Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	if errors.Is(err, otp.ErrQuotaExceeded) {
+		return echo.NewHTTPError(http.StatusTooManyRequests, map[string]string{
+			"error":   ReasonQuotaExceeded,
+			"message": "monthly send quota exceeded",
+		})
+	}
+	return echo.NewHTTPError(http.StatusTooManyRequests, map[string]string{
+		"error":   ReasonRateLimited,
+		"reason":  err.Reason,
+		"message": "too many codes sent to this phone number",
+	})
+}
@@ -0,0 +1,147 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/panbeh/otp-backend/internal/domain/apikey"
+	"github.com/panbeh/otp-backend/internal/domain/business"
+)
+
+type OTPSettingsManager interface {
+	Get(ctx context.Context, businessID string) (business.Business, error)
+	SetAllowedCountries(ctx context.Context, businessID string, codes []string) (business.Business, error)
+	SetCodeFormat(ctx context.Context, businessID string, length int, charset string) (business.Business, error)
+	SetOTPTTL(ctx context.Context, businessID string, ttl time.Duration) (business.Business, error)
+}
+
+type setAllowedCountriesRequest struct {
+	CountryCodes []string `json:"country_codes"`
+}
+
+type setCodeFormatRequest struct {
+	Length  int    `json:"length"`
+	Charset string `json:"charset"`
+}
+
+type setOTPTTLRequest struct {
+	TTLSeconds int64 `json:"ttl_seconds"`
+}
+
+// otpSettingsResponse shows the business's own settings; an empty list, zero or ""
+// means the deployment default applies.
+type otpSettingsResponse struct {
+	AllowedCountries []string `json:"allowed_countries"`
+	CodeLength       int      `json:"code_length"`
+	CodeCharset      string   `json:"code_charset"`
+	TTLSeconds       int64    `json:"ttl_seconds"`
+}
+
+func newOTPSettingsResponse(b business.Business) otpSettingsResponse {
+	countries := b.AllowedCountries
+	if countries == nil {
+		countries = []string{}
+	}
+	return otpSettingsResponse{
+		AllowedCountries: countries,
+		CodeLength:       b.CodeLength,
+		CodeCharset:      b.CodeCharset,
+		TTLSeconds:       int64(b.OTPTTL.Seconds()),
+	}
+}
+
+// OTPSettingsHandler serves GET /business/otp-settings and the PUTs below it:
+// /countries replaces the country calling codes the business may send codes to,
+// /code-format sets the length and charset of its codes and /ttl how long they stay
+// valid. All of them act on the business of the caller's API key; reading needs
+// admin:read and changing needs admin:write.
+type OTPSettingsHandler struct {
+	settings OTPSettingsManager
+	auth     Authenticator
+	logger   *slog.Logger
+}
+
+func NewOTPSettingsHandler(settings OTPSettingsManager, auth Authenticator, logger *slog.Logger) *OTPSettingsHandler {
+	return &OTPSettingsHandler{settings: settings, auth: auth, logger: logger}
+}
+
+func (h *OTPSettingsHandler) Register(e *echo.Echo) {
+	g := e.Group("/business/otp-settings")
+	g.GET("", h.get, h.auth.RequireScope(apikey.ScopeAdminRead))
+	g.PUT("/countries", h.setCountries, h.auth.RequireScope(apikey.ScopeAdminWrite))
+	g.PUT("/code-format", h.setCodeFormat, h.auth.RequireScope(apikey.ScopeAdminWrite))
+	g.PUT("/ttl", h.setTTL, h.auth.RequireScope(apikey.ScopeAdminWrite))
+}
+
+func (h *OTPSettingsHandler) get(c echo.Context) error {
+	caller, _ := APIKeyFromContext(c)
+	b, err := h.settings.Get(c.Request().Context(), caller.BusinessID)
+	if err != nil {
+		return h.fail(c, "otp_settings_get_failed", err)
+	}
+	return c.JSON(http.StatusOK, newOTPSettingsResponse(b))
+}
+
+func (h *OTPSettingsHandler) setCountries(c echo.Context) error {
+	caller, _ := APIKeyFromContext(c)
+	var req setAllowedCountriesRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	b, err := h.settings.SetAllowedCountries(c.Request().Context(), caller.BusinessID, req.CountryCodes)
+	if err != nil {
+		return h.fail(c, "otp_settings_countries_failed", err)
+	}
+	return c.JSON(http.StatusOK, newOTPSettingsResponse(b))
+}
+
+func (h *OTPSettingsHandler) setCodeFormat(c echo.Context) error {
+	caller, _ := APIKeyFromContext(c)
+	var req setCodeFormatRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+
+	b, err := h.settings.SetCodeFormat(c.Request().Context(), caller.BusinessID, req.Length, req.Charset)
+	if err != nil {
+		return h.fail(c, "otp_settings_code_format_failed", err)
+	}
+	return c.JSON(http.StatusOK, newOTPSettingsResponse(b))
+}
+
+func (h *OTPSettingsHandler) setTTL(c echo.Context) error {
+	caller, _ := APIKeyFromContext(c)
+	var req setOTPTTLRequest
+	if err := c.Bind(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+	}
+	// Checked before converting, so a huge value can't overflow into a valid duration.
+	if req.TTLSeconds < 0 || req.TTLSeconds > math.MaxInt64/int64(time.Second) {
+		return echo.NewHTTPError(http.StatusBadRequest, business.ErrInvalidOTPTTL.Error())
+	}
+
+	b, err := h.settings.SetOTPTTL(c.Request().Context(), caller.BusinessID, time.Duration(req.TTLSeconds)*time.Second)
+	if err != nil {
+		return h.fail(c, "otp_settings_ttl_failed", err)
+	}
+	return c.JSON(http.StatusOK, newOTPSettingsResponse(b))
+}
+
+func (h *OTPSettingsHandler) fail(c echo.Context, msg string, err error) error {
+	switch {
+	case errors.Is(err, business.ErrInvalidCountryCode), errors.Is(err, business.ErrInvalidCodeFormat),
+		errors.Is(err, business.ErrInvalidOTPTTL):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, business.ErrNotFound):
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	h.logger.ErrorContext(c.Request().Context(), msg, slog.Any("err", err))
+	return echo.NewHTTPError(http.StatusInternalServerError)
+}
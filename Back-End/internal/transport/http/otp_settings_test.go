@@ -0,0 +1,133 @@
+package transport_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/panbeh/otp-backend/internal/domain/apikey"
+	"github.com/panbeh/otp-backend/internal/domain/business"
+	transport "github.com/panbeh/otp-backend/internal/transport/http"
+)
+
+// fakeOTPSettings keeps business b1's settings and validates them with the real domain rules.
+type fakeOTPSettings struct {
+	b business.Business
+}
+
+func (f *fakeOTPSettings) Get(ctx context.Context, businessID string) (business.Business, error) {
+	if businessID != f.b.ID {
+		return business.Business{}, business.ErrNotFound
+	}
+	return f.b, nil
+}
+
+func (f *fakeOTPSettings) SetAllowedCountries(ctx context.Context, businessID string, codes []string) (business.Business, error) {
+	b, err := business.NewService(business.ServiceConfig{}).SetAllowedCountries(f.b, codes)
+	if err == nil {
+		f.b = b
+	}
+	return b, err
+}
+
+func (f *fakeOTPSettings) SetCodeFormat(ctx context.Context, businessID string, length int, charset string) (business.Business, error) {
+	b, err := business.NewService(business.ServiceConfig{}).SetCodeFormat(f.b, length, charset)
+	if err == nil {
+		f.b = b
+	}
+	return b, err
+}
+
+func (f *fakeOTPSettings) SetOTPTTL(ctx context.Context, businessID string, ttl time.Duration) (business.Business, error) {
+	b, err := business.NewService(business.ServiceConfig{}).SetOTPTTL(f.b, ttl)
+	if err == nil {
+		f.b = b
+	}
+	return b, err
+}
+
+func newOTPSettingsServer(settings *fakeOTPSettings) *echo.Echo {
+	e := echo.New()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	keys := &fakeKeys{keys: map[string]apikey.APIKey{
+		"reader": {ID: "k1", BusinessID: "b1", Scopes: []apikey.Scope{apikey.ScopeAdminRead}},
+		"writer": {ID: "k2", BusinessID: "b1", Scopes: []apikey.Scope{apikey.ScopeAdminWrite}},
+	}}
+	transport.NewOTPSettingsHandler(settings, transport.Authenticator{Keys: keys, Logger: logger}, logger).Register(e)
+	return e
+}
+
+type otpSettingsBody struct {
+	AllowedCountries []string `json:"allowed_countries"`
+	CodeLength       int      `json:"code_length"`
+	CodeCharset      string   `json:"code_charset"`
+	TTLSeconds       int64    `json:"ttl_seconds"`
+}
+
+func TestOTPSettingsHandler_Countries(t *testing.T) {
+	settings := &fakeOTPSettings{b: business.Business{ID: "b1"}}
+	e := newOTPSettingsServer(settings)
+
+	if rec := serve(e, http.MethodPut, "/business/otp-settings/countries", "", `{"country_codes":["98"]}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without key, got %d", rec.Code)
+	}
+	if rec := serve(e, http.MethodPut, "/business/otp-settings/countries", "reader", `{"country_codes":["98"]}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a key without admin:write, got %d", rec.Code)
+	}
+	if rec := serve(e, http.MethodPut, "/business/otp-settings/countries", "writer", `{"country_codes":["98","abc"]}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid code, got %d", rec.Code)
+	}
+	if rec := serve(e, http.MethodPut, "/business/otp-settings/countries", "writer", `{"country_codes":["+98","971"]}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+
+	rec := serve(e, http.MethodGet, "/business/otp-settings", "reader", "")
+	var body otpSettingsBody
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusOK || !reflect.DeepEqual(body.AllowedCountries, []string{"98", "971"}) {
+		t.Fatalf("expected the stored allowlist, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestOTPSettingsHandler_CodeFormat(t *testing.T) {
+	settings := &fakeOTPSettings{b: business.Business{ID: "b1"}}
+	e := newOTPSettingsServer(settings)
+
+	for _, body := range []string{`{"length":3,"charset":"numeric"}`, `{"length":6,"charset":"hex"}`} {
+		if rec := serve(e, http.MethodPut, "/business/otp-settings/code-format", "writer", body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, rec.Code)
+		}
+	}
+
+	rec := serve(e, http.MethodPut, "/business/otp-settings/code-format", "writer", `{"length":8,"charset":"alphanumeric"}`)
+	var body otpSettingsBody
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusOK || body.CodeLength != 8 || body.CodeCharset != "alphanumeric" {
+		t.Fatalf("expected the new code format, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestOTPSettingsHandler_TTL(t *testing.T) {
+	settings := &fakeOTPSettings{b: business.Business{ID: "b1"}}
+	e := newOTPSettingsServer(settings)
+
+	for _, body := range []string{`{"ttl_seconds":-1}`, `{"ttl_seconds":9223372036854775807}`} {
+		if rec := serve(e, http.MethodPut, "/business/otp-settings/ttl", "writer", body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, rec.Code)
+		}
+	}
+
+	rec := serve(e, http.MethodPut, "/business/otp-settings/ttl", "writer", `{"ttl_seconds":120}`)
+	var body otpSettingsBody
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusOK || body.TTLSeconds != 120 || settings.b.OTPTTL != 2*time.Minute {
+		t.Fatalf("expected a 120s ttl, got %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,241 @@
+package transport_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/panbeh/otp-backend/internal/domain/apikey"
+	"github.com/panbeh/otp-backend/internal/domain/business"
+	"github.com/panbeh/otp-backend/internal/domain/delivery"
+	"github.com/panbeh/otp-backend/internal/domain/otp"
+	"github.com/panbeh/otp-backend/internal/service"
+	"github.com/panbeh/otp-backend/internal/sms"
+	transport "github.com/panbeh/otp-backend/internal/transport/http"
+)
+
+// fakeOTPs returns err from every call, accepts only the code "123456" and knows one
+// sent request, r1 of b1.
+type fakeOTPs struct {
+	err        error
+	businessID string
+}
+
+func (f *fakeOTPs) Send(ctx context.Context, businessID, phone string) (service.SentOTP, error) {
+	f.businessID = businessID
+	if f.err != nil {
+		return service.SentOTP{}, f.err
+	}
+	return service.SentOTP{RequestID: "r1", ExpiresAt: time.Unix(300, 0)}, nil
+}
+
+func (f *fakeOTPs) Verify(ctx context.Context, businessID, phone, code string) (bool, error) {
+	f.businessID = businessID
+	return code == "123456", f.err
+}
+
+func (f *fakeOTPs) Request(ctx context.Context, businessID, id string) (delivery.Delivery, error) {
+	if businessID != "b1" || id != "r1" {
+		return delivery.Delivery{}, delivery.ErrNotFound
+	}
+	return delivery.Delivery{ID: "r1", BusinessID: "b1", MaskedPhone: "+98912***6789", Status: delivery.StatusDelivered}, nil
+}
+
+func newOTPServer(otps transport.OTPSender) *echo.Echo {
+	keys := &fakeKeys{keys: map[string]apikey.APIKey{
+		"sender":   {ID: "k1", BusinessID: "b1", Scopes: []apikey.Scope{apikey.ScopeOTPSend}},
+		"verifier": {ID: "k2", BusinessID: "b1", Scopes: []apikey.Scope{apikey.ScopeOTPVerify}},
+	}}
+	return newOTPServerWithKeys(otps, keys)
+}
+
+func newOTPServerWithKeys(otps transport.OTPSender, keys transport.KeyResolver) *echo.Echo {
+	e := echo.New()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	transport.NewOTPHandler(otps, transport.Authenticator{Keys: keys, Logger: logger}, logger).Register(e)
+	return e
+}
+
+// suspendedKeys resolves every key to a suspended business, as APIKeyService does.
+type suspendedKeys struct{}
+
+func (suspendedKeys) Resolve(ctx context.Context, key string, scope apikey.Scope) (apikey.APIKey, error) {
+	return apikey.APIKey{}, business.ErrSuspended
+}
+
+func TestOTPHandler_EnforcesScopes(t *testing.T) {
+	otps := &fakeOTPs{}
+	e := newOTPServer(otps)
+	sendBody := `{"phone":"+989123456789"}`
+
+	if rec := serve(e, http.MethodPost, "/otp/send", "", sendBody); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without key, got %d", rec.Code)
+	}
+	if rec := serve(e, http.MethodPost, "/otp/send", "unknown", sendBody); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for an unknown key, got %d", rec.Code)
+	}
+	if rec := serve(e, http.MethodPost, "/otp/send", "verifier", sendBody); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a key without otp:send, got %d", rec.Code)
+	}
+	if otps.businessID != "" {
+		t.Fatalf("expected no call to the OTP service, got one for %q", otps.businessID)
+	}
+
+	if rec := serve(e, http.MethodPost, "/otp/send", "sender", sendBody); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d: %s", rec.Code, rec.Body)
+	}
+	if otps.businessID != "b1" {
+		t.Fatalf("expected the send for the caller's business, got %q", otps.businessID)
+	}
+	rec := serve(e, http.MethodPost, "/otp/verify", "verifier", `{"phone":"+989123456789","code":"123456"}`)
+	if rec.Code != http.StatusOK || rec.Body.String() != "{\"verified\":true}\n" {
+		t.Fatalf("expected 200 verified, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestOTPHandler_MapsErrors(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{
+		{otp.ErrInvalidPhone, http.StatusBadRequest},
+		{otp.ErrInvalidCode, http.StatusBadRequest},
+		{otp.ErrCountryNotAllowed, http.StatusBadRequest},
+		{otp.ErrTooManyAttempts, http.StatusTooManyRequests},
+		{sms.ErrDeliveryFailed, http.StatusBadGateway},
+		{sms.ErrNoProviderAvailable, http.StatusBadGateway},
+	} {
+		e := newOTPServer(&fakeOTPs{err: tc.err})
+		rec := serve(e, http.MethodPost, "/otp/verify", "verifier", `{"phone":"+989123456789","code":"123456"}`)
+		if rec.Code != tc.want {
+			t.Errorf("%v: expected %d, got %d", tc.err, tc.want, rec.Code)
+		}
+	}
+}
+
+func TestOTPHandler_RateLimited(t *testing.T) {
+	otps := &fakeOTPs{err: &otp.RateLimitError{Reason: otp.RateLimitReasonCooldown, RetryAfter: 41500 * time.Millisecond}}
+	e := newOTPServer(otps)
+
+	rec := serve(e, http.MethodPost, "/otp/send", "sender", `{"phone":"+989123456789"}`)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d: %s", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "42" {
+		t.Fatalf("expected Retry-After rounded up to 42, got %q", got)
+	}
+	var body struct {
+		Error  string `json:"error"`
+		Reason string `json:"reason"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if body.Error != transport.ReasonRateLimited || body.Reason != otp.RateLimitReasonCooldown {
+		t.Fatalf("unexpected body: %s", rec.Body)
+	}
+}
+
+func TestOTPHandler_Request(t *testing.T) {
+	e := newOTPServer(&fakeOTPs{})
+
+	rec := serve(e, http.MethodPost, "/otp/send", "sender", `{"phone":"+989123456789"}`)
+	var sent struct {
+		RequestID string `json:"request_id"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &sent)
+	if sent.RequestID != "r1" {
+		t.Fatalf("expected the send to return its request id, got %s", rec.Body)
+	}
+
+	rec = serve(e, http.MethodGet, "/otp/requests/r1", "sender", "")
+	var body struct {
+		ID     string `json:"id"`
+		Phone  string `json:"phone"`
+		Status string `json:"status"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusOK || body.ID != "r1" || body.Status != "delivered" || body.Phone != "+98912***6789" {
+		t.Fatalf("expected the delivery status, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := serve(e, http.MethodGet, "/otp/requests/r2", "sender", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown request, got %d", rec.Code)
+	}
+	if rec := serve(e, http.MethodGet, "/otp/requests/r1", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without key, got %d", rec.Code)
+	}
+	if rec := serve(e, http.MethodGet, "/otp/requests/r1", "verifier", ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a key without otp:send, got %d", rec.Code)
+	}
+}
+
+func TestOTPHandler_SuspendedBusiness(t *testing.T) {
+	for name, e := range map[string]*echo.Echo{
+		// Rejected while resolving the key.
+		"auth": newOTPServerWithKeys(&fakeOTPs{}, suspendedKeys{}),
+		// Suspended after the key resolved, caught by the OTP service.
+		"service": newOTPServer(&fakeOTPs{err: business.ErrSuspended}),
+	} {
+		for _, route := range []struct{ path, key, body string }{
+			{"/otp/send", "sender", `{"phone":"+989123456789"}`},
+			{"/otp/verify", "verifier", `{"phone":"+989123456789","code":"123456"}`},
+		} {
+			rec := serve(e, http.MethodPost, route.path, route.key, route.body)
+			var body struct {
+				Error string `json:"error"`
+			}
+			_ = json.Unmarshal(rec.Body.Bytes(), &body)
+			if rec.Code != http.StatusForbidden || body.Error != transport.ReasonBusinessSuspended {
+				t.Errorf("%s %s: expected 403 %s, got %d: %s", name, route.path, transport.ReasonBusinessSuspended, rec.Code, rec.Body)
+			}
+		}
+	}
+}
+
+func TestOTPHandler_SignedRequest(t *testing.T) {
+	otps := &fakeOTPs{}
+	verifier := &fakeVerifier{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	e := echo.New()
+	transport.NewOTPHandler(otps, transport.Authenticator{Keys: &fakeKeys{}, Signatures: verifier, Logger: logger}, logger).Register(e)
+
+	body := `{"phone":"+989123456789"}`
+	req := httptest.NewRequest(http.MethodPost, "/otp/send", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(transport.HeaderBusinessID, "b1")
+	req.Header.Set(transport.HeaderSignatureTimestamp, "1700000000")
+	req.Header.Set(transport.HeaderSignatureNonce, "nonce-0000000001")
+	req.Header.Set(transport.HeaderSignature, "abc")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted || otps.businessID != "b1" {
+		t.Fatalf("expected a signed send for b1, got %d (business %q): %s", rec.Code, otps.businessID, rec.Body)
+	}
+	if verifier.got.Path != "/otp/send" || string(verifier.got.Body) != body {
+		t.Fatalf("expected the signature checked over the send request, got %+v", verifier.got)
+	}
+}
+
+func TestOTPHandler_QuotaExceeded(t *testing.T) {
+	otps := &fakeOTPs{err: &otp.RateLimitError{Reason: otp.RateLimitReasonMonthlyQuota, RetryAfter: 36 * time.Hour}}
+	e := newOTPServer(otps)
+
+	rec := serve(e, http.MethodPost, "/otp/send", "sender", `{"phone":"+989123456789"}`)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "129600" {
+		t.Fatalf("expected 429 with Retry-After 129600, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if body.Error != transport.ReasonQuotaExceeded {
+		t.Fatalf("expected %s, got %s", transport.ReasonQuotaExceeded, rec.Body)
+	}
+}
@@ -0,0 +1,62 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/panbeh/otp-backend/internal/domain/apikey"
+	"github.com/panbeh/otp-backend/internal/domain/business"
+)
+
+type SigningSecretIssuer interface {
+	IssueSecret(ctx context.Context, issuer apikey.APIKey, keyID string) (string, error)
+}
+
+type issueSigningSecretRequest struct {
+	KeyID string `json:"key_id"`
+}
+
+// SigningSecretHandler serves POST /business/signing-secret, which needs admin:write.
+// It replaces the business's request-signing secret with one tied to the API key
+// key_id and returns the new secret once. Signed requests get that key's scopes, which
+// must all be held by the calling key.
+type SigningSecretHandler struct {
+	issuer SigningSecretIssuer
+	auth   Authenticator
+	logger *slog.Logger
+}
+
+func NewSigningSecretHandler(issuer SigningSecretIssuer, auth Authenticator, logger *slog.Logger) *SigningSecretHandler {
+	return &SigningSecretHandler{issuer: issuer, auth: auth, logger: logger}
+}
+
+func (h *SigningSecretHandler) Register(e *echo.Echo) {
+	e.POST("/business/signing-secret", h.issue, h.auth.RequireScope(apikey.ScopeAdminWrite))
+}
+
+func (h *SigningSecretHandler) issue(c echo.Context) error {
+	caller, _ := APIKeyFromContext(c)
+	var req issueSigningSecretRequest
+	if err := c.Bind(&req); err != nil || strings.TrimSpace(req.KeyID) == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "key_id is required")
+	}
+
+	secret, err := h.issuer.IssueSecret(c.Request().Context(), caller, req.KeyID)
+	switch {
+	case err == nil:
+		return c.JSON(http.StatusOK, map[string]string{"business_id": caller.BusinessID, "key_id": req.KeyID, "secret": secret})
+	case errors.Is(err, business.ErrNotFound), errors.Is(err, apikey.ErrNotFound):
+		return echo.NewHTTPError(http.StatusNotFound)
+	case errors.Is(err, apikey.ErrRevoked):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case errors.Is(err, apikey.ErrScopeDenied):
+		return echo.NewHTTPError(http.StatusForbidden, "cannot delegate scopes the calling key lacks")
+	}
+	h.logger.ErrorContext(c.Request().Context(), "signing_secret_issue_failed", slog.Any("err", err))
+	return echo.NewHTTPError(http.StatusInternalServerError)
+}
@@ -0,0 +1,64 @@
+package transport_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/panbeh/otp-backend/internal/domain/apikey"
+	transport "github.com/panbeh/otp-backend/internal/transport/http"
+)
+
+// fakeIssuer ties secrets to the keys it knows, refusing ones stronger than the issuer.
+type fakeIssuer struct {
+	keys   map[string]apikey.APIKey
+	issued []string
+}
+
+func (f *fakeIssuer) IssueSecret(ctx context.Context, issuer apikey.APIKey, keyID string) (string, error) {
+	k, ok := f.keys[keyID]
+	if !ok || k.BusinessID != issuer.BusinessID {
+		return "", apikey.ErrNotFound
+	}
+	if !issuer.HasScopes(k.Scopes) {
+		return "", apikey.ErrScopeDenied
+	}
+	f.issued = append(f.issued, issuer.BusinessID+"/"+keyID)
+	return "secret", nil
+}
+
+func TestSigningSecretHandler_Issue(t *testing.T) {
+	keys := &fakeKeys{keys: map[string]apikey.APIKey{
+		"owner":  {ID: "k1", BusinessID: "b1", Scopes: apikey.AllScopes},
+		"writer": {ID: "k2", BusinessID: "b1", Scopes: []apikey.Scope{apikey.ScopeAdminWrite}},
+	}}
+	issuer := &fakeIssuer{keys: map[string]apikey.APIKey{
+		"k-otp":   {ID: "k-otp", BusinessID: "b1", Scopes: apikey.OTPScopes},
+		"k-other": {ID: "k-other", BusinessID: "b2", Scopes: apikey.OTPScopes},
+	}}
+	e := echo.New()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	transport.NewSigningSecretHandler(issuer, transport.Authenticator{Keys: keys, Logger: logger}, logger).Register(e)
+
+	cases := []struct {
+		key, body string
+		want      int
+	}{
+		{"owner", `{}`, http.StatusBadRequest},
+		{"owner", `{"key_id":"k-other"}`, http.StatusNotFound},
+		{"writer", `{"key_id":"k-otp"}`, http.StatusForbidden},
+		{"owner", `{"key_id":"k-otp"}`, http.StatusOK},
+	}
+	for _, tc := range cases {
+		if rec := serve(e, http.MethodPost, "/business/signing-secret", tc.key, tc.body); rec.Code != tc.want {
+			t.Errorf("%s %s: expected %d, got %d", tc.key, tc.body, tc.want, rec.Code)
+		}
+	}
+	if len(issuer.issued) != 1 || issuer.issued[0] != "b1/k-otp" {
+		t.Fatalf("expected one secret for b1/k-otp, got %v", issuer.issued)
+	}
+}
@@ -0,0 +1,166 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/panbeh/otp-backend/internal/config"
+	"github.com/panbeh/otp-backend/internal/domain/delivery"
+)
+
+// Delivery-report webhook headers. A provider either signs the raw body with the
+// shared secret (hex HMAC-SHA256 in HeaderWebhookSignature) or, when it can only set
+// fixed headers, sends the secret itself in HeaderWebhookSecret.
+const (
+	HeaderWebhookSignature = "X-Webhook-Signature"
+	HeaderWebhookSecret    = "X-Webhook-Secret"
+)
+
+// maxWebhookBodySize caps how much of a delivery report is buffered to check its signature.
+const maxWebhookBodySize = 64 << 10
+
+// reportRetryAfter is how long providers are asked to wait before retrying a report
+// that arrived ahead of its send.
+const reportRetryAfter = "5"
+
+// Kavenegar delivery statuses that settle a message; everything else is in-flight.
+var kavenegarFinalStatuses = map[int]struct {
+	delivered bool
+	reason    string
+}{
+	6:  {false, "failed"},
+	10: {true, ""},
+	11: {false, "undelivered"},
+	13: {false, "canceled"},
+	14: {false, "blocked"},
+}
+
+type DeliveryReporter interface {
+	Report(ctx context.Context, provider, messageID string, delivered bool, reason string) error
+}
+
+type genericReport struct {
+	MessageID string `json:"message_id"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason"`
+}
+
+// SMSReportHandler accepts delivery-report callbacks from SMS providers at
+// POST /webhooks/sms/:provider. Only providers that return message IDs, which
+// reports refer back to, are accepted.
+type SMSReportHandler struct {
+	reports DeliveryReporter
+	secret  string
+	logger  *slog.Logger
+}
+
+func NewSMSReportHandler(reports DeliveryReporter, secret string, logger *slog.Logger) *SMSReportHandler {
+	return &SMSReportHandler{reports: reports, secret: secret, logger: logger}
+}
+
+func (h *SMSReportHandler) Register(e *echo.Echo) {
+	e.POST("/webhooks/sms/:provider", h.handle)
+}
+
+func (h *SMSReportHandler) handle(c echo.Context) error {
+	if err := h.authenticate(c.Request()); err != nil {
+		return err
+	}
+
+	provider := c.Param("provider")
+	var (
+		messageID string
+		delivered bool
+		reason    string
+	)
+	switch config.SMSProvider(provider) {
+	case config.SMSProviderKavenegar:
+		// Kavenegar posts form fields: messageid, status.
+		status, err := strconv.Atoi(c.FormValue("status"))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid status")
+		}
+		final, ok := kavenegarFinalStatuses[status]
+		if !ok {
+			return c.NoContent(http.StatusNoContent)
+		}
+		messageID, delivered, reason = c.FormValue("messageid"), final.delivered, final.reason
+	case config.SMSProviderSMSIR:
+		var r genericReport
+		if err := c.Bind(&r); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+		}
+		switch delivery.Status(r.Status) {
+		case delivery.StatusDelivered:
+			delivered = true
+		case delivery.StatusFailed:
+		default:
+			return c.NoContent(http.StatusNoContent)
+		}
+		messageID, reason = r.MessageID, r.Reason
+	default:
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+	if messageID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "missing message id")
+	}
+
+	err := h.reports.Report(c.Request().Context(), provider, messageID, delivered, reason)
+	switch {
+	case err == nil, errors.Is(err, delivery.ErrInvalidStatus):
+		// Duplicate or late reports are acknowledged so providers stop retrying.
+		return c.NoContent(http.StatusNoContent)
+	case errors.Is(err, delivery.ErrNotFound), errors.Is(err, delivery.ErrNotSent), errors.Is(err, delivery.ErrStatusConflict):
+		// The report beat the send being recorded, or raced another update; answering
+		// with a retryable status keeps it from being lost.
+		c.Response().Header().Set("Retry-After", reportRetryAfter)
+		return echo.NewHTTPError(http.StatusServiceUnavailable)
+	}
+	h.logger.ErrorContext(c.Request().Context(), "delivery_report_failed",
+		slog.String("provider", provider),
+		slog.String("message_id", messageID),
+		slog.Any("err", err),
+	)
+	return echo.NewHTTPError(http.StatusInternalServerError)
+}
+
+// authenticate checks the report's signature, or failing that its secret header.
+// The body is put back for the handler to parse.
+func (h *SMSReportHandler) authenticate(req *http.Request) error {
+	if h.secret == "" {
+		return echo.NewHTTPError(http.StatusUnauthorized)
+	}
+	if sig := req.Header.Get(HeaderWebhookSignature); sig != "" {
+		body, err := io.ReadAll(io.LimitReader(req.Body, maxWebhookBodySize+1))
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid body")
+		}
+		if len(body) > maxWebhookBodySize {
+			return echo.NewHTTPError(http.StatusRequestEntityTooLarge)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		mac := hmac.New(sha256.New, []byte(h.secret))
+		mac.Write(body)
+		want := hex.EncodeToString(mac.Sum(nil))
+		if !hmac.Equal([]byte(sig), []byte(want)) {
+			return echo.NewHTTPError(http.StatusUnauthorized)
+		}
+		return nil
+	}
+	if subtle.ConstantTimeCompare([]byte(req.Header.Get(HeaderWebhookSecret)), []byte(h.secret)) != 1 {
+		return echo.NewHTTPError(http.StatusUnauthorized)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package transport_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/panbeh/otp-backend/internal/domain/delivery"
+	transport "github.com/panbeh/otp-backend/internal/transport/http"
+)
+
+type report struct {
+	provider, messageID string
+	delivered           bool
+	reason              string
+}
+
+type fakeDeliveryReports struct {
+	reports []report
+	err     error
+}
+
+func (f *fakeDeliveryReports) Report(ctx context.Context, provider, messageID string, delivered bool, reason string) error {
+	f.reports = append(f.reports, report{provider, messageID, delivered, reason})
+	return f.err
+}
+
+func newWebhookServer(reports transport.DeliveryReporter) *echo.Echo {
+	e := echo.New()
+	transport.NewSMSReportHandler(reports, "s3cret", slog.New(slog.NewTextHandler(io.Discard, nil))).Register(e)
+	return e
+}
+
+func postReport(e *echo.Echo, provider, contentType, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/sms/"+provider, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, contentType)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestSMSReportHandler_KavenegarSigned(t *testing.T) {
+	reports := &fakeDeliveryReports{}
+	e := newWebhookServer(reports)
+
+	form := url.Values{"messageid": {"8792343"}, "status": {"10"}}.Encode()
+	rec := postReport(e, "kavenegar", echo.MIMEApplicationForm, form,
+		map[string]string{transport.HeaderWebhookSignature: sign("s3cret", form)})
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	if len(reports.reports) != 1 || reports.reports[0] != (report{"kavenegar", "8792343", true, ""}) {
+		t.Fatalf("unexpected reports: %+v", reports.reports)
+	}
+
+	rec = postReport(e, "kavenegar", echo.MIMEApplicationForm, form,
+		map[string]string{transport.HeaderWebhookSignature: sign("wrong", form)})
+	if rec.Code != http.StatusUnauthorized || len(reports.reports) != 1 {
+		t.Fatalf("expected 401 for a bad signature, got %d %+v", rec.Code, reports.reports)
+	}
+}
+
+func TestSMSReportHandler_GenericFailureAndAuth(t *testing.T) {
+	reports := &fakeDeliveryReports{}
+	e := newWebhookServer(reports)
+	body := `{"message_id":"77","status":"failed","reason":"unreachable"}`
+
+	for _, header := range []map[string]string{nil, {transport.HeaderWebhookSecret: "wrong"}} {
+		if rec := postReport(e, "smsir", echo.MIMEApplicationJSON, body, header); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401 for %v, got %d", header, rec.Code)
+		}
+	}
+	// The secret is no longer accepted in the query string.
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/sms/smsir?secret=s3cret", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || len(reports.reports) != 0 {
+		t.Fatalf("expected 401 without reports, got %d %+v", rec.Code, reports.reports)
+	}
+
+	secret := map[string]string{transport.HeaderWebhookSecret: "s3cret"}
+	if rec := postReport(e, "smsir", echo.MIMEApplicationJSON, body, secret); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	if len(reports.reports) != 1 || reports.reports[0] != (report{"smsir", "77", false, "unreachable"}) {
+		t.Fatalf("unexpected reports: %+v", reports.reports)
+	}
+
+	if rec := postReport(e, "http", echo.MIMEApplicationJSON, body, secret); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a provider without message ids, got %d", rec.Code)
+	}
+}
+
+func TestSMSReportHandler_EarlyReportsAreRetried(t *testing.T) {
+	secret := map[string]string{transport.HeaderWebhookSecret: "s3cret"}
+	body := `{"message_id":"77","status":"delivered"}`
+
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{delivery.ErrNotFound, http.StatusServiceUnavailable},
+		{delivery.ErrNotSent, http.StatusServiceUnavailable},
+		{delivery.ErrStatusConflict, http.StatusServiceUnavailable},
+		{delivery.ErrInvalidStatus, http.StatusNoContent},
+	}
+	for _, tc := range cases {
+		e := newWebhookServer(&fakeDeliveryReports{err: tc.err})
+		rec := postReport(e, "smsir", echo.MIMEApplicationJSON, body, secret)
+		if rec.Code != tc.want {
+			t.Errorf("%v: expected %d, got %d", tc.err, tc.want, rec.Code)
+		}
+		if tc.want == http.StatusServiceUnavailable && rec.Header().Get("Retry-After") == "" {
+			t.Errorf("%v: expected a Retry-After header", tc.err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/panbeh/otp-backend/internal/domain/apikey"
+	"github.com/panbeh/otp-backend/internal/domain/usage"
+)
+
+const usageDateLayout = "2006-01-02"
+
+type UsageReporter interface {
+	Report(ctx context.Context, businessID string, from, to time.Time, granularity usage.Granularity) ([]usage.Summary, error)
+}
+
+type usageBucketResponse struct {
+	Period          string `json:"period"`
+	Sends           int64  `json:"sends"`
+	VerifySucceeded int64  `json:"verify_succeeded"`
+	VerifyFailed    int64  `json:"verify_failed"`
+	Expired         int64  `json:"expired"`
+}
+
+type usageResponse struct {
+	From        string                `json:"from"`
+	To          string                `json:"to"`
+	Granularity usage.Granularity     `json:"granularity"`
+	Buckets     []usageBucketResponse `json:"buckets"`
+}
+
+// UsageHandler serves GET /usage?from=YYYY-MM-DD&to=YYYY-MM-DD&granularity=day|month
+// to the calling business (admin:read). Dates are UTC and inclusive; by default the
+// report covers the current month so far, by day.
+type UsageHandler struct {
+	reports UsageReporter
+	auth    Authenticator
+	logger  *slog.Logger
+	now     func() time.Time
+}
+
+func NewUsageHandler(reports UsageReporter, auth Authenticator, logger *slog.Logger) *UsageHandler {
+	return &UsageHandler{reports: reports, auth: auth, logger: logger, now: time.Now}
+}
+
+func (h *UsageHandler) Register(e *echo.Echo) {
+	e.GET("/usage", h.report, h.auth.RequireScope(apikey.ScopeAdminRead))
+}
+
+func (h *UsageHandler) report(c echo.Context) error {
+	caller, _ := APIKeyFromContext(c)
+
+	today := usage.Day(h.now())
+	to, err := queryDate(c, "to", today)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid to")
+	}
+	from, err := queryDate(c, "from", usage.MonthStart(to))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid from")
+	}
+	granularity := usage.GranularityDay
+	if v := c.QueryParam("granularity"); v != "" {
+		if granularity, err = usage.NewGranularity(v); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid granularity")
+		}
+	}
+
+	summaries, err := h.reports.Report(c.Request().Context(), caller.BusinessID, from, to, granularity)
+	switch {
+	case errors.Is(err, usage.ErrInvalidRange):
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	case err != nil:
+		h.logger.ErrorContext(c.Request().Context(), "usage_report_failed", slog.Any("err", err))
+		return echo.NewHTTPError(http.StatusInternalServerError)
+	}
+
+	res := usageResponse{
+		From:        from.Format(usageDateLayout),
+		To:          to.Format(usageDateLayout),
+		Granularity: granularity,
+		Buckets:     make([]usageBucketResponse, 0, len(summaries)),
+	}
+	for _, s := range summaries {
+		res.Buckets = append(res.Buckets, usageBucketResponse{
+			Period:          s.Period.Format(usageDateLayout),
+			Sends:           s.Sends,
+			VerifySucceeded: s.VerifySucceeded,
+			VerifyFailed:    s.VerifyFailed,
+			Expired:         s.Expired,
+		})
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+func queryDate(c echo.Context, name string, def time.Time) (time.Time, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return def, nil
+	}
+	return time.Parse(usageDateLayout, v)
+}
@@ -0,0 +1,90 @@
+package transport_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/panbeh/otp-backend/internal/domain/apikey"
+	"github.com/panbeh/otp-backend/internal/domain/usage"
+	transport "github.com/panbeh/otp-backend/internal/transport/http"
+)
+
+// fakeReporter has one send on 2026-03-02 and applies the real range rules.
+type fakeReporter struct {
+	businessID string
+}
+
+func (f *fakeReporter) Report(ctx context.Context, businessID string, from, to time.Time, granularity usage.Granularity) ([]usage.Summary, error) {
+	f.businessID = businessID
+	q, err := usage.NewService(usage.ServiceConfig{}).NewReportQuery(businessID, from, to, granularity)
+	if err != nil {
+		return nil, err
+	}
+	return usage.Fill(q, []usage.Summary{{Period: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), Sends: 1}}), nil
+}
+
+func newUsageServer(reports transport.UsageReporter) *echo.Echo {
+	keys := &fakeKeys{keys: map[string]apikey.APIKey{
+		"reader": {ID: "k1", BusinessID: "b1", Scopes: []apikey.Scope{apikey.ScopeAdminRead}},
+		"sender": {ID: "k2", BusinessID: "b1", Scopes: []apikey.Scope{apikey.ScopeOTPSend}},
+	}}
+	e := echo.New()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	transport.NewUsageHandler(reports, transport.Authenticator{Keys: keys, Logger: logger}, logger).Register(e)
+	return e
+}
+
+func TestUsageHandler_Report(t *testing.T) {
+	reports := &fakeReporter{}
+	e := newUsageServer(reports)
+
+	if rec := serve(e, http.MethodGet, "/usage", "sender", ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 without admin:read, got %d", rec.Code)
+	}
+
+	rec := serve(e, http.MethodGet, "/usage?from=2026-03-01&to=2026-03-03", "reader", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Granularity string `json:"granularity"`
+		Buckets     []struct {
+			Period string `json:"period"`
+			Sends  int64  `json:"sends"`
+		} `json:"buckets"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if reports.businessID != "b1" || body.Granularity != "day" || len(body.Buckets) != 3 {
+		t.Fatalf("unexpected report: %s", rec.Body)
+	}
+	if body.Buckets[1].Period != "2026-03-02" || body.Buckets[1].Sends != 1 || body.Buckets[0].Sends != 0 {
+		t.Fatalf("expected empty days to be filled in, got %s", rec.Body)
+	}
+
+	rec = serve(e, http.MethodGet, "/usage?from=2026-01-15&to=2026-03-03&granularity=month", "reader", "")
+	_ = json.Unmarshal(rec.Body.Bytes(), &body)
+	if rec.Code != http.StatusOK || len(body.Buckets) != 3 || body.Buckets[2].Period != "2026-03-01" || body.Buckets[2].Sends != 1 {
+		t.Fatalf("unexpected monthly report: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestUsageHandler_RejectsBadQueries(t *testing.T) {
+	e := newUsageServer(&fakeReporter{})
+	for _, q := range []string{
+		"?from=yesterday",
+		"?granularity=week",
+		"?from=2026-03-05&to=2026-03-01",
+		"?from=2024-01-01&to=2026-03-01",
+	} {
+		if rec := serve(e, http.MethodGet, "/usage"+q, "reader", ""); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %q, got %d", q, rec.Code)
+		}
+	}
+}